@@ -0,0 +1,218 @@
+// Package codegen 根据查询条件生成各语言官方驱动的调用代码
+package codegen
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Query 要生成代码的查询；Pipeline不为空时生成聚合查询，忽略其他条件
+type Query struct {
+	Database   string
+	Collection string
+	Filter     bson.D
+	Sort       bson.D
+	Projection bson.D
+	Skip       int64
+	Limit      int64
+	Pipeline   []bson.D
+}
+
+// Languages 支持的语言
+var Languages = []string{"go", "node", "python", "java"}
+
+// lineWidth 超过该宽度的文档和数组分多行书写
+const lineWidth = 80
+
+// Generate 生成指定语言的代码，BSON类型（ObjectId、日期、Int64、Decimal128、正则表达式等）使用对应驱动中的类型表示
+func Generate(language string, query Query) (string, error) {
+	var lang dialect
+	switch language {
+	case "go":
+		lang = goDialect{}
+	case "node":
+		lang = nodeDialect{}
+	case "python":
+		lang = pythonDialect{}
+	case "java":
+		lang = javaDialect{}
+	default:
+		return "", fmt.Errorf("unsupported language %q, supported languages are %s", language, strings.Join(Languages, ", "))
+	}
+
+	g := &generator{dialect: lang, imports: make(map[string]bool), names: make(map[string]string)}
+	return lang.program(g, query)
+}
+
+// dialect 一种语言的写法
+type dialect interface {
+	indent() string
+	// scalar 书写文档和数组以外的值
+	scalar(g *generator, v interface{}) (string, error)
+	// document 由已书写的键和值组成文档，depth为所在的缩进层级
+	document(keys, values []string, depth int) string
+	array(items []string, depth int) string
+	program(g *generator, query Query) (string, error)
+}
+
+// generator 一次代码生成的状态
+type generator struct {
+	dialect dialect
+	imports map[string]bool
+	// 需要在查询之前声明的变量，如Go中的ObjectID
+	declarations []string
+	names        map[string]string
+}
+
+// code 原样输出的代码片段
+type code string
+
+// use 记录用到的导入
+func (g *generator) use(imports ...string) {
+	for _, name := range imports {
+		g.imports[name] = true
+	}
+}
+
+// importList 按名称排序的导入
+func (g *generator) importList() []string {
+	list := make([]string, 0, len(g.imports))
+	for name := range g.imports {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// declare 声明一个变量，相同的初始化代码只声明一次，返回变量名
+func (g *generator) declare(prefix, init string, declaration func(name string) string) string {
+	if name, ok := g.names[init]; ok {
+		return name
+	}
+	name := fmt.Sprintf("%s%d", prefix, len(g.names)+1)
+	g.names[init] = name
+	g.declarations = append(g.declarations, declaration(name))
+	return name
+}
+
+// value 书写任意BSON值
+func (g *generator) value(v interface{}, depth int) (string, error) {
+	switch x := v.(type) {
+	case bson.D:
+		keys := make([]string, len(x))
+		values := make([]string, len(x))
+		for i, e := range x {
+			keys[i] = e.Key
+			value, err := g.value(e.Value, depth+1)
+			if err != nil {
+				return "", err
+			}
+			values[i] = value
+		}
+		return g.dialect.document(keys, values, depth), nil
+	case bson.A:
+		items := make([]string, len(x))
+		for i, item := range x {
+			value, err := g.value(item, depth+1)
+			if err != nil {
+				return "", err
+			}
+			items[i] = value
+		}
+		return g.dialect.array(items, depth), nil
+	case []bson.D:
+		items := make(bson.A, len(x))
+		for i, stage := range x {
+			items[i] = stage
+		}
+		return g.value(items, depth)
+	case code:
+		return string(x), nil
+	}
+	return g.dialect.scalar(g, v)
+}
+
+// nonNil 未指定的查询条件使用空文档
+func nonNil(doc bson.D) bson.D {
+	if doc == nil {
+		return bson.D{}
+	}
+	return doc
+}
+
+// fitsInline 子元素都是单行且总宽度不超过行宽时写在一行
+func fitsInline(parts []string, depth int, indent string, overhead int) bool {
+	width := depth*len(indent) + overhead
+	for _, part := range parts {
+		if strings.Contains(part, "\n") {
+			return false
+		}
+		width += len(part) + 2
+	}
+	return width <= lineWidth
+}
+
+// quote 书写带引号的字符串，使用 \uXXXX 转义控制字符，适用于JavaScript、Python和Java
+func quote(s string, q rune) string {
+	var b strings.Builder
+	b.WriteRune(q)
+	for _, r := range s {
+		switch {
+		case r == q || r == '\\':
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r < 0x20 || r == 0x7f || r == 0x2028 || r == 0x2029:
+			fmt.Fprintf(&b, `\u%04x`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteRune(q)
+	return b.String()
+}
+
+// formatFloat 书写浮点数，整数值保留 ".0" 以免被当作整数；NaN和无穷大返回false
+func formatFloat(f float64) (string, bool) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "", false
+	}
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eE") {
+		s += ".0"
+	}
+	return s, true
+}
+
+// utc 将BSON日期转换为UTC时间
+func utc(d primitive.DateTime) time.Time {
+	return d.Time().UTC()
+}
+
+// isoDate 毫秒精度的ISO 8601时间
+func isoDate(d primitive.DateTime) string {
+	return utc(d).Format("2006-01-02T15:04:05.000Z")
+}
+
+// base64Data 二进制数据的Base64编码
+func base64Data(b primitive.Binary) string {
+	return base64.StdEncoding.EncodeToString(b.Data)
+}
+
+// unsupported 无法在目标语言中表示的BSON类型
+func unsupported(v interface{}) error {
+	return fmt.Errorf("unsupported BSON value of type %T", v)
+}
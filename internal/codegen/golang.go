@@ -0,0 +1,192 @@
+package codegen
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// goDialect Go官方驱动，文档使用 bson.D 保持字段顺序
+type goDialect struct{}
+
+func (goDialect) indent() string { return "\t" }
+
+func (d goDialect) scalar(g *generator, v interface{}) (string, error) {
+	switch x := v.(type) {
+	case nil:
+		return "nil", nil
+	case string:
+		return strconv.Quote(x), nil
+	case bool:
+		return strconv.FormatBool(x), nil
+	case int32:
+		// 无类型的整数常量编码为int32
+		return strconv.FormatInt(int64(x), 10), nil
+	case int64:
+		return fmt.Sprintf("int64(%d)", x), nil
+	case float64:
+		if s, ok := formatFloat(x); ok {
+			return s, nil
+		}
+		g.use("math")
+		switch {
+		case x > 0:
+			return "math.Inf(1)", nil
+		case x < 0:
+			return "math.Inf(-1)", nil
+		}
+		return "math.NaN()", nil
+	case primitive.ObjectID:
+		g.use("go.mongodb.org/mongo-driver/bson/primitive")
+		return g.declare("objectID", x.Hex(), func(name string) string {
+			return fmt.Sprintf("%s, err := primitive.ObjectIDFromHex(%q)\nif err != nil {\n\treturn err\n}", name, x.Hex())
+		}), nil
+	case primitive.Decimal128:
+		g.use("go.mongodb.org/mongo-driver/bson/primitive")
+		return g.declare("decimal", x.String(), func(name string) string {
+			return fmt.Sprintf("%s, err := primitive.ParseDecimal128(%q)\nif err != nil {\n\treturn err\n}", name, x.String())
+		}), nil
+	case primitive.DateTime:
+		g.use("time")
+		t := utc(x)
+		return fmt.Sprintf("time.Date(%d, time.%s, %d, %d, %d, %d, %d, time.UTC)",
+			t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond()), nil
+	case primitive.Regex:
+		g.use("go.mongodb.org/mongo-driver/bson/primitive")
+		return fmt.Sprintf("primitive.Regex{Pattern: %s, Options: %s}", strconv.Quote(x.Pattern), strconv.Quote(x.Options)), nil
+	case primitive.Timestamp:
+		g.use("go.mongodb.org/mongo-driver/bson/primitive")
+		return fmt.Sprintf("primitive.Timestamp{T: %d, I: %d}", x.T, x.I), nil
+	case primitive.Binary:
+		g.use("go.mongodb.org/mongo-driver/bson/primitive")
+		bytes := make([]string, len(x.Data))
+		for i, b := range x.Data {
+			bytes[i] = fmt.Sprintf("0x%02x", b)
+		}
+		return fmt.Sprintf("primitive.Binary{Subtype: 0x%02x, Data: []byte{%s}}", x.Subtype, strings.Join(bytes, ", ")), nil
+	case primitive.MinKey:
+		g.use("go.mongodb.org/mongo-driver/bson/primitive")
+		return "primitive.MinKey{}", nil
+	case primitive.MaxKey:
+		g.use("go.mongodb.org/mongo-driver/bson/primitive")
+		return "primitive.MaxKey{}", nil
+	}
+	return "", unsupported(v)
+}
+
+func (d goDialect) document(keys, values []string, depth int) string {
+	entries := make([]string, len(keys))
+	for i := range keys {
+		entries[i] = fmt.Sprintf("{Key: %s, Value: %s}", strconv.Quote(keys[i]), values[i])
+	}
+	return d.list("bson.D", entries, depth)
+}
+
+func (d goDialect) array(items []string, depth int) string {
+	return d.list("bson.A", items, depth)
+}
+
+// list 复合字面量，分多行时每个元素后都有逗号
+func (d goDialect) list(typ string, items []string, depth int) string {
+	if fitsInline(items, depth, d.indent(), len(typ)+2) {
+		return typ + "{" + strings.Join(items, ", ") + "}"
+	}
+	inner := strings.Repeat(d.indent(), depth+1)
+	var b strings.Builder
+	b.WriteString(typ + "{\n")
+	for _, item := range items {
+		b.WriteString(inner + item + ",\n")
+	}
+	b.WriteString(strings.Repeat(d.indent(), depth) + "}")
+	return b.String()
+}
+
+func (d goDialect) program(g *generator, q Query) (string, error) {
+	g.use("go.mongodb.org/mongo-driver/bson")
+	var body strings.Builder
+	fmt.Fprintf(&body, "coll := client.Database(%q).Collection(%q)\n\n", q.Database, q.Collection)
+
+	if len(q.Pipeline) > 0 {
+		g.use("go.mongodb.org/mongo-driver/mongo")
+		// mongo.Pipeline 是 []bson.D，元素可以省略类型
+		stages := make([]string, len(q.Pipeline))
+		for i, stage := range q.Pipeline {
+			value, err := g.value(stage, 1)
+			if err != nil {
+				return "", err
+			}
+			stages[i] = strings.TrimPrefix(value, "bson.D")
+		}
+		body.WriteString("pipeline := mongo.Pipeline{\n")
+		for _, stage := range stages {
+			body.WriteString("\t" + stage + ",\n")
+		}
+		body.WriteString("}\n\ncursor, err := coll.Aggregate(ctx, pipeline)\n")
+	} else {
+		filter, err := g.value(nonNil(q.Filter), 0)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&body, "filter := %s\n", filter)
+
+		var setters []string
+		if len(q.Sort) > 0 {
+			sort, err := g.value(q.Sort, 1)
+			if err != nil {
+				return "", err
+			}
+			setters = append(setters, "SetSort("+sort+")")
+		}
+		if len(q.Projection) > 0 {
+			projection, err := g.value(q.Projection, 1)
+			if err != nil {
+				return "", err
+			}
+			setters = append(setters, "SetProjection("+projection+")")
+		}
+		if q.Skip > 0 {
+			setters = append(setters, fmt.Sprintf("SetSkip(%d)", q.Skip))
+		}
+		if q.Limit > 0 {
+			setters = append(setters, fmt.Sprintf("SetLimit(%d)", q.Limit))
+		}
+		if len(setters) > 0 {
+			g.use("go.mongodb.org/mongo-driver/mongo/options")
+			body.WriteString("opts := options.Find().\n\t" + strings.Join(setters, ".\n\t") + "\n")
+			body.WriteString("\ncursor, err := coll.Find(ctx, filter, opts)\n")
+		} else {
+			body.WriteString("\ncursor, err := coll.Find(ctx, filter)\n")
+		}
+	}
+	body.WriteString("if err != nil {\n\treturn err\n}\ndefer cursor.Close(ctx)\n\n")
+	body.WriteString("var results []bson.M\nif err := cursor.All(ctx, &results); err != nil {\n\treturn err\n}\n")
+
+	// 标准库和第三方包分组导入
+	var std, thirdParty []string
+	for _, name := range g.importList() {
+		if strings.Contains(name, ".") {
+			thirdParty = append(thirdParty, "\t"+strconv.Quote(name))
+		} else {
+			std = append(std, "\t"+strconv.Quote(name))
+		}
+	}
+	groups := []string{}
+	for _, group := range [][]string{std, thirdParty} {
+		if len(group) > 0 {
+			groups = append(groups, strings.Join(group, "\n"))
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("import (\n" + strings.Join(groups, "\n\n") + "\n)\n\n")
+	for _, declaration := range g.declarations {
+		b.WriteString(declaration + "\n")
+	}
+	if len(g.declarations) > 0 {
+		b.WriteString("\n")
+	}
+	b.WriteString(body.String())
+	return b.String(), nil
+}
@@ -0,0 +1,167 @@
+package codegen
+
+import (
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// javaDialect Java同步驱动，文档使用 org.bson.Document 的链式 append
+type javaDialect struct{}
+
+func (javaDialect) indent() string { return "    " }
+
+// continuation 续行缩进为两级
+func (d javaDialect) continuation(depth int) string {
+	return strings.Repeat(d.indent(), depth+2)
+}
+
+func (d javaDialect) scalar(g *generator, v interface{}) (string, error) {
+	switch x := v.(type) {
+	case nil:
+		return "null", nil
+	case string:
+		return quote(x, '"'), nil
+	case bool:
+		return fmt.Sprint(x), nil
+	case int32:
+		return fmt.Sprint(x), nil
+	case int64:
+		return fmt.Sprintf("%dL", x), nil
+	case float64:
+		if s, ok := formatFloat(x); ok {
+			return s, nil
+		}
+		switch {
+		case x > 0:
+			return "Double.POSITIVE_INFINITY", nil
+		case x < 0:
+			return "Double.NEGATIVE_INFINITY", nil
+		}
+		return "Double.NaN", nil
+	case primitive.ObjectID:
+		g.use("org.bson.types.ObjectId")
+		return fmt.Sprintf("new ObjectId(%q)", x.Hex()), nil
+	case primitive.Decimal128:
+		g.use("org.bson.types.Decimal128")
+		return fmt.Sprintf("Decimal128.parse(%q)", x.String()), nil
+	case primitive.DateTime:
+		g.use("java.time.Instant", "java.util.Date")
+		return fmt.Sprintf("Date.from(Instant.parse(%q))", isoDate(x)), nil
+	case primitive.Regex:
+		g.use("org.bson.BsonRegularExpression")
+		return fmt.Sprintf("new BsonRegularExpression(%s, %s)", quote(x.Pattern, '"'), quote(x.Options, '"')), nil
+	case primitive.Timestamp:
+		g.use("org.bson.BsonTimestamp")
+		return fmt.Sprintf("new BsonTimestamp(%d, %d)", x.T, x.I), nil
+	case primitive.Binary:
+		g.use("org.bson.types.Binary", "java.util.Base64")
+		return fmt.Sprintf("new Binary((byte) %d, Base64.getDecoder().decode(%q))", x.Subtype, base64Data(x)), nil
+	case primitive.MinKey:
+		g.use("org.bson.types.MinKey")
+		return "new MinKey()", nil
+	case primitive.MaxKey:
+		g.use("org.bson.types.MaxKey")
+		return "new MaxKey()", nil
+	}
+	return "", unsupported(v)
+}
+
+// document 第一个字段通过构造函数传入，其余字段链式append；值在depth+2层书写，与续行缩进一致
+func (d javaDialect) document(keys, values []string, depth int) string {
+	if len(keys) == 0 {
+		return "new Document()"
+	}
+	first := fmt.Sprintf("new Document(%s, %s)", quote(keys[0], '"'), values[0])
+	appends := make([]string, len(keys)-1)
+	for i := 1; i < len(keys); i++ {
+		appends[i-1] = fmt.Sprintf(".append(%s, %s)", quote(keys[i], '"'), values[i])
+	}
+	if fitsInline(append([]string{first}, appends...), depth, d.indent(), 0) {
+		return first + strings.Join(appends, "")
+	}
+	if len(appends) == 0 {
+		return first
+	}
+	return first + "\n" + d.continuation(depth) + strings.Join(appends, "\n"+d.continuation(depth))
+}
+
+func (d javaDialect) array(items []string, depth int) string {
+	if fitsInline(items, depth, d.indent(), 15) {
+		return "Arrays.asList(" + strings.Join(items, ", ") + ")"
+	}
+	return "Arrays.asList(\n" + d.continuation(depth) + strings.Join(items, ",\n"+d.continuation(depth)) + ")"
+}
+
+func (d javaDialect) program(g *generator, q Query) (string, error) {
+	g.use("com.mongodb.client.MongoCollection", "org.bson.Document", "java.util.ArrayList", "java.util.List")
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "MongoCollection<Document> collection = client.getDatabase(%s).getCollection(%s);\n\n", quote(q.Database, '"'), quote(q.Collection, '"'))
+
+	if len(q.Pipeline) > 0 {
+		// 阶段位于续行缩进处，按第2层书写
+		stages := make([]string, len(q.Pipeline))
+		for i, stage := range q.Pipeline {
+			value, err := g.value(stage, 2)
+			if err != nil {
+				return "", err
+			}
+			stages[i] = value
+		}
+		fmt.Fprintf(&body, "List<Document> pipeline = Arrays.asList(\n%s%s);\n\n", d.continuation(0), strings.Join(stages, ",\n"+d.continuation(0)))
+		body.WriteString("List<Document> results = collection.aggregate(pipeline).into(new ArrayList<>());\n")
+	} else {
+		filter, err := g.value(nonNil(q.Filter), 0)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&body, "Document filter = %s;\n\n", filter)
+
+		calls := []string{"find(filter)"}
+		if len(q.Projection) > 0 {
+			projection, err := g.value(q.Projection, 2)
+			if err != nil {
+				return "", err
+			}
+			calls = append(calls, "projection("+projection+")")
+		}
+		if len(q.Sort) > 0 {
+			sort, err := g.value(q.Sort, 2)
+			if err != nil {
+				return "", err
+			}
+			calls = append(calls, "sort("+sort+")")
+		}
+		if q.Skip > 0 {
+			calls = append(calls, fmt.Sprintf("skip(%d)", q.Skip))
+		}
+		if q.Limit > 0 {
+			calls = append(calls, fmt.Sprintf("limit(%d)", q.Limit))
+		}
+		calls = append(calls, "into(new ArrayList<>())")
+		body.WriteString("List<Document> results = collection." + strings.Join(calls, "\n"+d.continuation(0)+".") + ";\n")
+	}
+
+	if strings.Contains(body.String(), "Arrays.asList(") {
+		g.use("java.util.Arrays")
+	}
+
+	// java.* 的导入放在最后
+	var b strings.Builder
+	var std []string
+	for _, name := range g.importList() {
+		if strings.HasPrefix(name, "java.") {
+			std = append(std, "import "+name+";")
+		} else {
+			b.WriteString("import " + name + ";\n")
+		}
+	}
+	b.WriteString("\n")
+	if len(std) > 0 {
+		b.WriteString(strings.Join(std, "\n") + "\n\n")
+	}
+	b.WriteString(body.String())
+	return b.String(), nil
+}
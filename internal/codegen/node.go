@@ -0,0 +1,158 @@
+package codegen
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// jsIdentifier 可以不加引号作为对象键的名称
+var jsIdentifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// nodeDialect Node.js官方驱动
+type nodeDialect struct{}
+
+func (nodeDialect) indent() string { return "  " }
+
+func (d nodeDialect) scalar(g *generator, v interface{}) (string, error) {
+	switch x := v.(type) {
+	case nil:
+		return "null", nil
+	case string:
+		return quote(x, '\''), nil
+	case bool:
+		return fmt.Sprint(x), nil
+	case int32:
+		return fmt.Sprint(x), nil
+	case int64:
+		g.use("Long")
+		return fmt.Sprintf("Long.fromString('%d')", x), nil
+	case float64:
+		// 整数值的double使用Double包装，否则驱动会编码为int32
+		if s, ok := formatFloat(x); ok {
+			if strings.HasSuffix(s, ".0") {
+				g.use("Double")
+				return fmt.Sprintf("new Double(%s)", s), nil
+			}
+			return s, nil
+		}
+		switch {
+		case x > 0:
+			return "Infinity", nil
+		case x < 0:
+			return "-Infinity", nil
+		}
+		return "NaN", nil
+	case primitive.ObjectID:
+		g.use("ObjectId")
+		return fmt.Sprintf("new ObjectId('%s')", x.Hex()), nil
+	case primitive.Decimal128:
+		g.use("Decimal128")
+		return fmt.Sprintf("Decimal128.fromString('%s')", x.String()), nil
+	case primitive.DateTime:
+		return fmt.Sprintf("new Date('%s')", isoDate(x)), nil
+	case primitive.Regex:
+		g.use("BSONRegExp")
+		return fmt.Sprintf("new BSONRegExp(%s, %s)", quote(x.Pattern, '\''), quote(x.Options, '\'')), nil
+	case primitive.Timestamp:
+		g.use("Timestamp")
+		return fmt.Sprintf("new Timestamp({ t: %d, i: %d })", x.T, x.I), nil
+	case primitive.Binary:
+		g.use("Binary")
+		return fmt.Sprintf("new Binary(Buffer.from('%s', 'base64'), %d)", base64Data(x), x.Subtype), nil
+	case primitive.MinKey:
+		g.use("MinKey")
+		return "new MinKey()", nil
+	case primitive.MaxKey:
+		g.use("MaxKey")
+		return "new MaxKey()", nil
+	}
+	return "", unsupported(v)
+}
+
+func (d nodeDialect) document(keys, values []string, depth int) string {
+	if len(keys) == 0 {
+		return "{}"
+	}
+	entries := make([]string, len(keys))
+	for i, key := range keys {
+		if !jsIdentifier.MatchString(key) {
+			key = quote(key, '\'')
+		}
+		entries[i] = key + ": " + values[i]
+	}
+	if fitsInline(entries, depth, d.indent(), 4) {
+		return "{ " + strings.Join(entries, ", ") + " }"
+	}
+	return d.block("{", "}", entries, depth)
+}
+
+func (d nodeDialect) array(items []string, depth int) string {
+	if fitsInline(items, depth, d.indent(), 2) {
+		return "[" + strings.Join(items, ", ") + "]"
+	}
+	return d.block("[", "]", items, depth)
+}
+
+func (d nodeDialect) block(open, close string, items []string, depth int) string {
+	inner := strings.Repeat(d.indent(), depth+1)
+	return open + "\n" + inner + strings.Join(items, ",\n"+inner) + "\n" + strings.Repeat(d.indent(), depth) + close
+}
+
+func (d nodeDialect) program(g *generator, q Query) (string, error) {
+	var body strings.Builder
+	fmt.Fprintf(&body, "const collection = client.db(%s).collection(%s);\n\n", quote(q.Database, '\''), quote(q.Collection, '\''))
+
+	if len(q.Pipeline) > 0 {
+		pipeline, err := g.value(q.Pipeline, 0)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&body, "const pipeline = %s;\n\nconst results = await collection.aggregate(pipeline).toArray();\n", pipeline)
+	} else {
+		filter, err := g.value(nonNil(q.Filter), 0)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&body, "const filter = %s;\n", filter)
+
+		options := findOptions(q)
+		if len(options) > 0 {
+			value, err := g.value(options, 0)
+			if err != nil {
+				return "", err
+			}
+			fmt.Fprintf(&body, "const options = %s;\n\nconst results = await collection.find(filter, options).toArray();\n", value)
+		} else {
+			body.WriteString("\nconst results = await collection.find(filter).toArray();\n")
+		}
+	}
+
+	var b strings.Builder
+	if imports := g.importList(); len(imports) > 0 {
+		fmt.Fprintf(&b, "const { %s } = require('mongodb');\n\n", strings.Join(imports, ", "))
+	}
+	b.WriteString(body.String())
+	return b.String(), nil
+}
+
+// findOptions find查询的选项文档，skip和limit原样书写为数字
+func findOptions(q Query) bson.D {
+	var options bson.D
+	if len(q.Projection) > 0 {
+		options = append(options, bson.E{Key: "projection", Value: q.Projection})
+	}
+	if len(q.Sort) > 0 {
+		options = append(options, bson.E{Key: "sort", Value: q.Sort})
+	}
+	if q.Skip > 0 {
+		options = append(options, bson.E{Key: "skip", Value: code(fmt.Sprint(q.Skip))})
+	}
+	if q.Limit > 0 {
+		options = append(options, bson.E{Key: "limit", Value: code(fmt.Sprint(q.Limit))})
+	}
+	return options
+}
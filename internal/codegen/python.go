@@ -0,0 +1,182 @@
+package codegen
+
+import (
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// pythonDialect PyMongo，字典保持插入顺序，可以直接表示有序文档
+type pythonDialect struct{}
+
+func (pythonDialect) indent() string { return "    " }
+
+func (d pythonDialect) scalar(g *generator, v interface{}) (string, error) {
+	switch x := v.(type) {
+	case nil:
+		return "None", nil
+	case string:
+		return quote(x, '"'), nil
+	case bool:
+		if x {
+			return "True", nil
+		}
+		return "False", nil
+	case int32:
+		return fmt.Sprint(x), nil
+	case int64:
+		// 在int32范围内的int会被编码为int32，需要用Int64保留类型
+		g.use("from bson import Int64")
+		return fmt.Sprintf("Int64(%d)", x), nil
+	case float64:
+		if s, ok := formatFloat(x); ok {
+			return s, nil
+		}
+		switch {
+		case x > 0:
+			return `float("inf")`, nil
+		case x < 0:
+			return `float("-inf")`, nil
+		}
+		return `float("nan")`, nil
+	case primitive.ObjectID:
+		g.use("from bson import ObjectId")
+		return fmt.Sprintf("ObjectId(%q)", x.Hex()), nil
+	case primitive.Decimal128:
+		g.use("from bson import Decimal128")
+		return fmt.Sprintf("Decimal128(%q)", x.String()), nil
+	case primitive.DateTime:
+		g.use("from datetime import datetime, timezone")
+		t := utc(x)
+		return fmt.Sprintf("datetime(%d, %d, %d, %d, %d, %d, %d, tzinfo=timezone.utc)",
+			t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond()/1000), nil
+	case primitive.Regex:
+		g.use("from bson import Regex")
+		return fmt.Sprintf("Regex(%s, %s)", quote(x.Pattern, '"'), quote(x.Options, '"')), nil
+	case primitive.Timestamp:
+		g.use("from bson import Timestamp")
+		return fmt.Sprintf("Timestamp(%d, %d)", x.T, x.I), nil
+	case primitive.Binary:
+		g.use("from bson import Binary", "import base64")
+		return fmt.Sprintf("Binary(base64.b64decode(%q), %d)", base64Data(x), x.Subtype), nil
+	case primitive.MinKey:
+		g.use("from bson import MinKey")
+		return "MinKey()", nil
+	case primitive.MaxKey:
+		g.use("from bson import MaxKey")
+		return "MaxKey()", nil
+	}
+	return "", unsupported(v)
+}
+
+func (d pythonDialect) document(keys, values []string, depth int) string {
+	entries := make([]string, len(keys))
+	for i, key := range keys {
+		entries[i] = quote(key, '"') + ": " + values[i]
+	}
+	return d.list("{", "}", entries, depth)
+}
+
+func (d pythonDialect) array(items []string, depth int) string {
+	return d.list("[", "]", items, depth)
+}
+
+// list 分多行时每个元素后都有逗号
+func (d pythonDialect) list(open, close string, items []string, depth int) string {
+	if fitsInline(items, depth, d.indent(), 2) {
+		return open + strings.Join(items, ", ") + close
+	}
+	inner := strings.Repeat(d.indent(), depth+1)
+	var b strings.Builder
+	b.WriteString(open + "\n")
+	for _, item := range items {
+		b.WriteString(inner + item + ",\n")
+	}
+	b.WriteString(strings.Repeat(d.indent(), depth) + close)
+	return b.String()
+}
+
+func (d pythonDialect) program(g *generator, q Query) (string, error) {
+	var body strings.Builder
+	fmt.Fprintf(&body, "collection = client[%s][%s]\n\n", quote(q.Database, '"'), quote(q.Collection, '"'))
+
+	if len(q.Pipeline) > 0 {
+		pipeline, err := g.value(q.Pipeline, 0)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&body, "pipeline = %s\n\nresults = list(collection.aggregate(pipeline))\n", pipeline)
+	} else {
+		query, err := g.value(nonNil(q.Filter), 0)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&body, "query = %s\n", query)
+
+		// PyMongo的sort参数是 (字段, 方向) 元组的列表
+		args := []string{"query"}
+		if len(q.Projection) > 0 {
+			projection, err := g.value(q.Projection, 1)
+			if err != nil {
+				return "", err
+			}
+			args = append(args, "projection="+projection)
+		}
+		if len(q.Sort) > 0 {
+			pairs := make([]string, len(q.Sort))
+			for i, e := range q.Sort {
+				direction, err := g.value(e.Value, 2)
+				if err != nil {
+					return "", err
+				}
+				pairs[i] = fmt.Sprintf("(%s, %s)", quote(e.Key, '"'), direction)
+			}
+			args = append(args, "sort="+d.list("[", "]", pairs, 1))
+		}
+		if q.Skip > 0 {
+			args = append(args, fmt.Sprintf("skip=%d", q.Skip))
+		}
+		if q.Limit > 0 {
+			args = append(args, fmt.Sprintf("limit=%d", q.Limit))
+		}
+		if len(args) == 1 {
+			body.WriteString("\nresults = list(collection.find(query))\n")
+		} else {
+			body.WriteString("\ncursor = collection.find(\n")
+			for _, arg := range args {
+				body.WriteString(d.indent() + arg + ",\n")
+			}
+			body.WriteString(")\nresults = list(cursor)\n")
+		}
+	}
+
+	var b strings.Builder
+	if imports := g.importList(); len(imports) > 0 {
+		b.WriteString(strings.Join(mergePythonImports(imports), "\n") + "\n\n")
+	}
+	b.WriteString(body.String())
+	return b.String(), nil
+}
+
+// mergePythonImports 合并来自同一模块的 from ... import 语句
+func mergePythonImports(imports []string) []string {
+	var plain, modules []string
+	names := make(map[string][]string)
+	for _, line := range imports {
+		module, imported, ok := strings.Cut(strings.TrimPrefix(line, "from "), " import ")
+		if !strings.HasPrefix(line, "from ") || !ok {
+			plain = append(plain, line)
+			continue
+		}
+		if _, exists := names[module]; !exists {
+			modules = append(modules, module)
+		}
+		names[module] = append(names[module], imported)
+	}
+	result := plain
+	for _, module := range modules {
+		result = append(result, fmt.Sprintf("from %s import %s", module, strings.Join(names[module], ", ")))
+	}
+	return result
+}
@@ -0,0 +1,301 @@
+package codegen
+
+import (
+	"fmt"
+	"go/format"
+	"go/token"
+	"m-db-ui/internal/database"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// initialisms Go命名中整体大写的缩写
+var initialisms = map[string]bool{
+	"acl": true, "api": true, "ascii": true, "cpu": true, "css": true, "dns": true, "eof": true,
+	"guid": true, "html": true, "http": true, "https": true, "id": true, "ip": true, "json": true,
+	"lhs": true, "qps": true, "ram": true, "rhs": true, "rpc": true, "sku": true, "sla": true,
+	"smtp": true, "sql": true, "ssh": true, "tcp": true, "tls": true, "ttl": true, "udp": true,
+	"ui": true, "uid": true, "uri": true, "url": true, "utf8": true, "uuid": true, "vm": true,
+	"xml": true,
+}
+
+// Structs 根据结构分析结果生成Go结构体定义，子文档生成独立的嵌套类型。
+// 类型取各字段出现最多的BSON类型（数值类型合并为能容纳所有取值的类型），
+// 未出现在所有文档中或取值含null的字段使用指针并加 omitempty；typeName为空时由集合名推断
+func Structs(analysis *database.SchemaAnalysis, packageName, typeName string) (string, error) {
+	if packageName == "" {
+		packageName = "models"
+	}
+	if typeName == "" {
+		typeName = singular(goName(analysis.Collection))
+	}
+	if !token.IsIdentifier(packageName) {
+		return "", fmt.Errorf("invalid package name %q", packageName)
+	}
+	if !token.IsIdentifier(typeName) || !token.IsExported(typeName) {
+		return "", fmt.Errorf("invalid type name %q, must be an exported Go identifier", typeName)
+	}
+
+	w := &structWriter{
+		children: make(map[string][]*database.FieldStats),
+		imports:  make(map[string]bool),
+		names:    make(map[string]bool),
+	}
+	for _, field := range analysis.Fields {
+		parent := ""
+		if i := strings.LastIndex(field.Path, "."); i >= 0 {
+			parent = field.Path[:i]
+		}
+		w.children[parent] = append(w.children[parent], field)
+	}
+	comment := fmt.Sprintf("// %s 对应 %s.%s 集合中的文档，根据 %d 个采样文档生成\n", typeName, analysis.Database, analysis.Collection, analysis.Sampled)
+	w.structType(typeName, "", analysis.Sampled, comment)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "package %s\n\n", packageName)
+	switch {
+	case w.imports["time"] && w.imports["primitive"]:
+		b.WriteString("import (\n\t\"time\"\n\n\t\"go.mongodb.org/mongo-driver/bson/primitive\"\n)\n\n")
+	case w.imports["time"]:
+		b.WriteString("import \"time\"\n\n")
+	case w.imports["primitive"]:
+		b.WriteString("import \"go.mongodb.org/mongo-driver/bson/primitive\"\n\n")
+	}
+	b.WriteString(strings.Join(w.types, "\n"))
+
+	source, err := format.Source([]byte(b.String()))
+	if err != nil {
+		return "", err
+	}
+	return string(source), nil
+}
+
+// structWriter 生成结构体定义的状态
+type structWriter struct {
+	children map[string][]*database.FieldStats
+	imports  map[string]bool
+	// 按生成顺序排列的类型定义，外层类型在前
+	types []string
+	names map[string]bool
+}
+
+// structType 生成path下字段组成的结构体，parentCount为该子文档出现的次数，为0时所有字段都视为可选；返回类型名
+func (w *structWriter) structType(name, path string, parentCount int64, comment string) string {
+	name = uniqueName(name, w.names)
+	index := len(w.types)
+	w.types = append(w.types, "")
+
+	fields := append([]*database.FieldStats(nil), w.children[path]...)
+	sort.SliceStable(fields, func(i, k int) bool {
+		return fieldKey(fields[i]) == "_id" && fieldKey(fields[k]) != "_id"
+	})
+
+	var b strings.Builder
+	b.WriteString(comment)
+	fmt.Fprintf(&b, "type %s struct {\n", name)
+	fieldNames := make(map[string]bool)
+	for _, field := range fields {
+		key := fieldKey(field)
+		fieldName := uniqueName(goName(key), fieldNames)
+		optional := parentCount == 0 || field.Count < parentCount || field.Types["null"] > 0
+
+		tag := key
+		if optional {
+			tag += ",omitempty"
+		}
+		tags := fmt.Sprintf("bson:%q json:%q", tag, tag)
+		if strings.Contains(tags, "`") {
+			tags = strconv.Quote(tags)
+		} else {
+			tags = "`" + tags + "`"
+		}
+		fmt.Fprintf(&b, "\t%s %s %s\n", fieldName, w.fieldType(field, name+fieldName, optional), tags)
+	}
+	b.WriteString("}\n")
+
+	w.types[index] = b.String()
+	return name
+}
+
+// fieldType 字段的Go类型，nested为子文档类型的名称
+func (w *structWriter) fieldType(field *database.FieldStats, nested string, optional bool) string {
+	types := nonNullTypes(field.Types)
+	var typ string
+	switch {
+	case len(types) == 1 && types[0] == "object":
+		typ = w.structType(nested, field.Path, field.Types["object"], "")
+	case len(types) == 1 && types[0] == "array":
+		return "[]" + w.elementType(field, singular(nested))
+	default:
+		var ok bool
+		if typ, ok = w.scalarType(types); !ok {
+			return "interface{}"
+		}
+	}
+	if optional {
+		return "*" + typ
+	}
+	return typ
+}
+
+// elementType 数组元素的Go类型；无法确定子文档元素中字段是否必有，以包含数组的文档数近似
+func (w *structWriter) elementType(field *database.FieldStats, nested string) string {
+	types := nonNullTypes(field.ArrayTypes)
+	nullable := field.ArrayTypes["null"] > 0
+	var typ string
+	switch {
+	case len(types) == 1 && types[0] == "object":
+		typ = w.structType(nested, field.Path, field.Count, "")
+	case len(types) == 1 && types[0] == "array":
+		return "[]interface{}"
+	default:
+		var ok bool
+		if typ, ok = w.scalarType(types); !ok {
+			return "interface{}"
+		}
+	}
+	if nullable {
+		return "*" + typ
+	}
+	return typ
+}
+
+// scalarType 将标量BSON类型映射为Go类型，多种数值类型合并为能容纳所有取值的类型，其他混合类型返回false。
+// Decimal128只能从decimal解码，与其他数值类型混合时同样返回false
+func (w *structWriter) scalarType(types []string) (string, bool) {
+	numeric := make(map[string]bool)
+	for _, t := range types {
+		switch t {
+		case "int", "long", "double", "decimal":
+			numeric[t] = true
+		}
+	}
+	if len(numeric) > 0 && len(numeric) == len(types) {
+		switch {
+		case numeric["decimal"]:
+			if len(numeric) > 1 {
+				return "", false
+			}
+			w.imports["primitive"] = true
+			return "primitive.Decimal128", true
+		case numeric["double"]:
+			return "float64", true
+		case numeric["long"]:
+			return "int64", true
+		}
+		return "int32", true
+	}
+	if len(types) != 1 {
+		return "", false
+	}
+
+	switch types[0] {
+	case "string":
+		return "string", true
+	case "bool":
+		return "bool", true
+	case "date":
+		w.imports["time"] = true
+		return "time.Time", true
+	}
+	primitives := map[string]string{
+		"objectId":   "ObjectID",
+		"timestamp":  "Timestamp",
+		"binData":    "Binary",
+		"regex":      "Regex",
+		"javascript": "JavaScript",
+		"symbol":     "Symbol",
+		"minKey":     "MinKey",
+		"maxKey":     "MaxKey",
+	}
+	if name, ok := primitives[types[0]]; ok {
+		w.imports["primitive"] = true
+		return "primitive." + name, true
+	}
+	return "", false
+}
+
+// nonNullTypes 除null以外的类型，按名称排序
+func nonNullTypes(types map[string]int64) []string {
+	var list []string
+	for t := range types {
+		if t != "null" && t != "undefined" {
+			list = append(list, t)
+		}
+	}
+	sort.Strings(list)
+	return list
+}
+
+// fieldKey 字段路径的最后一段
+func fieldKey(field *database.FieldStats) string {
+	return field.Path[strings.LastIndex(field.Path, ".")+1:]
+}
+
+// goName 将字段名转换为导出的Go标识符，如 created_at、createdAt 都转换为 CreatedAt，user_id 转换为 UserID
+func goName(key string) string {
+	var words []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = nil
+		}
+	}
+	runes := []rune(key)
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+		case unicode.IsUpper(r) && len(word) > 0 && (unicode.IsLower(word[len(word)-1]) ||
+			(i+1 < len(runes) && unicode.IsLower(runes[i+1]))):
+			// camelCase 和 HTTPServer 的分词位置
+			flush()
+			word = append(word, r)
+		default:
+			word = append(word, r)
+		}
+	}
+	flush()
+
+	var b strings.Builder
+	for _, w := range words {
+		if initialisms[strings.ToLower(w)] {
+			b.WriteString(strings.ToUpper(w))
+			continue
+		}
+		r := []rune(w)
+		b.WriteString(string(unicode.ToUpper(r[0])) + string(r[1:]))
+	}
+	name := b.String()
+	if name == "" || !unicode.IsUpper([]rune(name)[0]) {
+		name = "Field" + name
+	}
+	return name
+}
+
+// singular 将复数形式的名称转换为单数，用于集合名和数组元素的类型名
+func singular(name string) string {
+	switch {
+	case strings.HasSuffix(name, "ies") && len(name) > 3:
+		return name[:len(name)-3] + "y"
+	case strings.HasSuffix(name, "sses") || strings.HasSuffix(name, "uses") || strings.HasSuffix(name, "xes"):
+		return name[:len(name)-2]
+	case strings.HasSuffix(name, "s") && !strings.HasSuffix(name, "ss") &&
+		!strings.HasSuffix(name, "us") && !strings.HasSuffix(name, "is") && len(name) > 1:
+		return name[:len(name)-1]
+	}
+	return name
+}
+
+// uniqueName 名称重复时添加数字后缀
+func uniqueName(name string, used map[string]bool) string {
+	result := name
+	for i := 2; used[result]; i++ {
+		result = fmt.Sprintf("%s%d", name, i)
+	}
+	used[result] = true
+	return result
+}
@@ -1,34 +1,530 @@
 package config
 
 import (
+	"fmt"
+	"log"
+	"m-db-ui/internal/database"
+	"net"
 	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
+// Config 应用配置，按 默认值 → YAML配置文件 → 环境变量 → 命令行参数 的顺序逐层覆盖
 type Config struct {
-	Host     string
-	Port     string
-	MongoURI string
+	Server     ServerConfig     `yaml:"server"`
+	MongoDB    MongoDBConfig    `yaml:"mongodb"`
+	CORS       CORSConfig       `yaml:"cors"`
+	Pagination PaginationConfig `yaml:"pagination"`
+	Logging    LoggingConfig    `yaml:"logging"`
+	Bulk       BulkConfig       `yaml:"bulk"`
+	Trash      TrashConfig      `yaml:"trash"`
+	Revisions  RevisionsConfig  `yaml:"revisions"`
+	Commands   CommandsConfig   `yaml:"commands"`
+	Shell      ShellConfig      `yaml:"shell"`
+}
+
+// ServerConfig 服务器配置
+type ServerConfig struct {
+	Host string `yaml:"host"`
+	Port string `yaml:"port"`
+	// TrustedProxies 受信任的反向代理（IP或CIDR）。只有来自这些地址的请求才使用
+	// X-Forwarded-For 和 X-Remote-User/X-Forwarded-User 请求头，为空时不信任任何代理
+	TrustedProxies []string `yaml:"trusted_proxies"`
 }
 
-func Load() *Config {
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8082"
+// TrustsProxy ip是否为受信任的反向代理
+func (s ServerConfig) TrustsProxy(ip string) bool {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, proxy := range s.TrustedProxies {
+		if _, network, err := net.ParseCIDR(proxy); err == nil {
+			if network.Contains(addr) {
+				return true
+			}
+		} else if proxyIP := net.ParseIP(proxy); proxyIP != nil && proxyIP.Equal(addr) {
+			return true
+		}
 	}
+	return false
+}
+
+// MongoDBConfig MongoDB客户端配置
+type MongoDBConfig struct {
+	Timeout      int           `yaml:"timeout"` // 连接超时(秒)
+	MaxPoolSize  uint64        `yaml:"max_pool_size"`
+	MinPoolSize  uint64        `yaml:"min_pool_size"`
+	ReadTimeout  time.Duration `yaml:"read_timeout"`
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+	AdminTimeout time.Duration `yaml:"admin_timeout"`
+	MaxQueryTime time.Duration `yaml:"max_query_time"` // 客户端maxTimeMS的上限
+}
 
-	host := os.Getenv("HOST")
-	if host == "" {
-		host = "127.0.0.1"
+// ConnectOptions 转换为客户端连接参数
+func (m MongoDBConfig) ConnectOptions() database.ConnectOptions {
+	return database.ConnectOptions{
+		Timeout:     time.Duration(m.Timeout) * time.Second,
+		MaxPoolSize: m.MaxPoolSize,
+		MinPoolSize: m.MinPoolSize,
 	}
+}
 
-	mongoURI := os.Getenv("MONGO_URI")
-	if mongoURI == "" {
-		mongoURI = "mongodb://localhost:27017"
+// Timeouts 转换为各类操作的超时配置
+func (m MongoDBConfig) Timeouts() database.Timeouts {
+	return database.Timeouts{
+		Read:     m.ReadTimeout,
+		Write:    m.WriteTimeout,
+		Admin:    m.AdminTimeout,
+		MaxQuery: m.MaxQueryTime,
 	}
+}
+
+// CORSConfig 跨域配置
+type CORSConfig struct {
+	AllowedOrigins []string `yaml:"allowed_origins"`
+	AllowedMethods []string `yaml:"allowed_methods"`
+	AllowedHeaders []string `yaml:"allowed_headers"`
+}
 
+// AllowAll 是否允许所有来源
+func (c CORSConfig) AllowAll() bool {
+	for _, origin := range c.AllowedOrigins {
+		if origin == "*" {
+			return true
+		}
+	}
+	return len(c.AllowedOrigins) == 0
+}
+
+// PaginationConfig 分页配置
+type PaginationConfig struct {
+	DefaultPageSize int64 `yaml:"default_page_size"`
+	MaxPageSize     int64 `yaml:"max_page_size"`
+}
+
+// LoggingConfig 日志配置
+type LoggingConfig struct {
+	Level  string `yaml:"level"`  // debug, info, warn, error
+	Format string `yaml:"format"` // json, text
+}
+
+// BulkConfig 批量更新/删除配置
+type BulkConfig struct {
+	MaxDocuments int64         `yaml:"max_documents"` // 单次批量操作允许影响的最大文档数，0表示不限制
+	PreviewTTL   time.Duration `yaml:"preview_ttl"`   // 预览令牌的有效期
+}
+
+// TrashConfig 回收站配置
+type TrashConfig struct {
+	Enabled       bool          `yaml:"enabled"`
+	Database      string        `yaml:"database"`       // 存放已删除文档和集合的数据库
+	RetentionDays int           `yaml:"retention_days"` // 保留天数，0表示永久保留
+	SweepInterval time.Duration `yaml:"sweep_interval"` // 清理过期条目的间隔
+}
+
+// Retention 回收站条目的保留时长，0表示永久保留
+func (t TrashConfig) Retention() time.Duration {
+	return time.Duration(t.RetentionDays) * 24 * time.Hour
+}
+
+// RevisionsConfig 文档历史版本配置
+type RevisionsConfig struct {
+	Enabled        bool     `yaml:"enabled"`
+	Collections    []string `yaml:"collections"`      // 记录历史版本的集合，格式为 "db.collection" 或 "db.*"，为空表示全部集合
+	MaxPerDocument int64    `yaml:"max_per_document"` // 每个文档最多保留的版本数，0表示不限制
+}
+
+// Tracks 是否记录指定集合的历史版本
+func (r RevisionsConfig) Tracks(dbName, collectionName string) bool {
+	if !r.Enabled {
+		return false
+	}
+	if len(r.Collections) == 0 {
+		return true
+	}
+	for _, pattern := range r.Collections {
+		if pattern == dbName+"."+collectionName || pattern == dbName+".*" {
+			return true
+		}
+	}
+	return false
+}
+
+// CommandsConfig 数据库命令控制台配置，按连接的角色限制可以执行的命令。
+// 角色只能在服务端配置中指定，不能通过连接管理接口修改
+type CommandsConfig struct {
+	Enabled     bool                     `yaml:"enabled"`
+	DefaultRole string                   `yaml:"default_role"` // 连接未指定角色时使用的角色
+	Connections map[string]string        `yaml:"connections"`  // 连接ID → 角色
+	Roles       map[string]CommandPolicy `yaml:"roles"`
+}
+
+// CommandPolicy 角色的命令白名单和黑名单：Allow不为空时只能执行其中的命令，Deny中的命令总是被拒绝
+type CommandPolicy struct {
+	Allow []string `yaml:"allow"`
+	Deny  []string `yaml:"deny"`
+}
+
+// Role 连接实际使用的角色，connections中未指定时使用默认角色
+func (c CommandsConfig) Role(connectionID string) string {
+	if role := c.Connections[connectionID]; role != "" {
+		return role
+	}
+	return c.DefaultRole
+}
+
+// Allows 角色是否可以执行命令，命令名不区分大小写；未配置的角色不能执行任何命令
+func (c CommandsConfig) Allows(role, command string) bool {
+	if !c.Enabled {
+		return false
+	}
+	policy, exists := c.Roles[role]
+	if !exists {
+		return false
+	}
+	for _, name := range policy.Deny {
+		if strings.EqualFold(name, command) {
+			return false
+		}
+	}
+	if len(policy.Allow) == 0 {
+		return true
+	}
+	for _, name := range policy.Allow {
+		if strings.EqualFold(name, command) {
+			return true
+		}
+	}
+	return false
+}
+
+// ShellConfig JavaScript脚本控制台配置
+type ShellConfig struct {
+	Enabled bool          `yaml:"enabled"`
+	Timeout time.Duration `yaml:"timeout"` // 单次执行的超时
+	Roles   []string      `yaml:"roles"`   // 可以使用脚本控制台的角色，与命令控制台的角色相同
+}
+
+// Allows 角色是否可以使用脚本控制台
+func (s ShellConfig) Allows(role string) bool {
+	if !s.Enabled {
+		return false
+	}
+	for _, name := range s.Roles {
+		if name == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Default 默认配置
+func Default() *Config {
 	return &Config{
-		Host:     host,
-		Port:     port,
-		MongoURI: mongoURI,
+		Server: ServerConfig{
+			Host: "127.0.0.1",
+			Port: "8082",
+		},
+		MongoDB: MongoDBConfig{
+			Timeout:      10,
+			MaxPoolSize:  100,
+			MinPoolSize:  0,
+			ReadTimeout:  5 * time.Second,
+			WriteTimeout: 5 * time.Second,
+			AdminTimeout: 10 * time.Second,
+			MaxQueryTime: time.Minute,
+		},
+		CORS: CORSConfig{
+			AllowedOrigins: []string{"*"},
+			AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+			AllowedHeaders: []string{"Origin", "Content-Type", "Accept", "Authorization", "If-Match"},
+		},
+		Pagination: PaginationConfig{
+			DefaultPageSize: 20,
+			MaxPageSize:     100,
+		},
+		Logging: LoggingConfig{
+			Level:  "info",
+			Format: "text",
+		},
+		Bulk: BulkConfig{
+			MaxDocuments: 10000,
+			PreviewTTL:   10 * time.Minute,
+		},
+		Trash: TrashConfig{
+			Enabled:       true,
+			Database:      "mdbui_trash",
+			RetentionDays: 30,
+			SweepInterval: time.Hour,
+		},
+		Revisions: RevisionsConfig{
+			Enabled:        true,
+			MaxPerDocument: 50,
+		},
+		Commands: CommandsConfig{
+			Enabled:     true,
+			DefaultRole: "readwrite",
+			Roles: map[string]CommandPolicy{
+				"readonly": {
+					Allow: []string{
+						"hello", "isMaster", "ping", "buildInfo", "hostInfo", "serverStatus", "connectionStatus",
+						"listDatabases", "listCollections", "listIndexes", "listCommands", "dbStats", "collStats", "dataSize",
+						"getParameter", "getCmdLineOpts", "getLog", "replSetGetStatus", "top", "validate",
+						"find", "count", "distinct", "explain", "usersInfo", "rolesInfo",
+					},
+				},
+				// readwrite 使用白名单：黑名单无法覆盖所有命令（例如 applyOps 可以间接执行 drop 等命令）；
+				// 黑名单保留危险命令，防止白名单被扩展时误加
+				"readwrite": {
+					Allow: []string{
+						"hello", "isMaster", "ping", "buildInfo", "hostInfo", "serverStatus", "connectionStatus",
+						"listDatabases", "listCollections", "listIndexes", "listCommands", "dbStats", "collStats", "dataSize",
+						"getParameter", "getCmdLineOpts", "getLog", "replSetGetStatus", "top", "validate",
+						"find", "count", "distinct", "explain", "usersInfo", "rolesInfo",
+						"aggregate", "getMore", "killCursors", "insert", "update", "delete", "findAndModify",
+						"create", "createIndexes", "dropIndexes", "collMod",
+					},
+					Deny: []string{
+						"shutdown", "dropDatabase", "fsync", "setParameter", "setFeatureCompatibilityVersion",
+						"replSetReconfig", "replSetStepDown", "createUser", "updateUser", "dropUser",
+						"dropAllUsersFromDatabase", "grantRolesToUser", "revokeRolesFromUser", "killOp",
+						"applyOps", "createRole", "updateRole", "dropRole", "dropAllRolesFromDatabase",
+						"grantRolesToRole", "revokeRolesFromRole", "grantPrivilegesToRole", "revokePrivilegesFromRole",
+					},
+				},
+				"admin": {
+					Deny: []string{"shutdown"},
+				},
+			},
+		},
+		Shell: ShellConfig{
+			Enabled: true,
+			Timeout: time.Minute,
+			Roles:   []string{"readwrite", "admin"},
+		},
+	}
+}
+
+// Load 加载配置：先取默认值，再依次应用YAML配置文件和环境变量。
+// path为空时跳过配置文件；required为false时配置文件不存在不视为错误
+func Load(path string, required bool) (*Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		if err := cfg.loadFile(path, required); err != nil {
+			return nil, err
+		}
+	}
+	cfg.loadEnv()
+
+	if err := cfg.validate(); err != nil {
+		return nil, err
 	}
-}
\ No newline at end of file
+	return cfg, nil
+}
+
+// loadFile 从YAML文件加载配置，文件中未出现的字段保持原值
+func (c *Config) loadFile(path string, required bool) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) && !required {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	// yaml会把角色合并进默认的map，文件中定义了角色时应完全替换默认角色
+	defaultRoles := c.Commands.Roles
+	c.Commands.Roles = nil
+	if err := yaml.Unmarshal(data, c); err != nil {
+		c.Commands.Roles = defaultRoles
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	if c.Commands.Roles == nil {
+		c.Commands.Roles = defaultRoles
+	}
+	return nil
+}
+
+// loadEnv 使用环境变量覆盖配置
+func (c *Config) loadEnv() {
+	setString(&c.Server.Host, "HOST")
+	setString(&c.Server.Port, "PORT")
+	setList(&c.Server.TrustedProxies, "TRUSTED_PROXIES")
+
+	setInt(&c.MongoDB.Timeout, "MONGO_TIMEOUT")
+	setUint(&c.MongoDB.MaxPoolSize, "MAX_POOL_SIZE")
+	setUint(&c.MongoDB.MinPoolSize, "MIN_POOL_SIZE")
+	setDuration(&c.MongoDB.ReadTimeout, "READ_TIMEOUT")
+	setDuration(&c.MongoDB.WriteTimeout, "WRITE_TIMEOUT")
+	setDuration(&c.MongoDB.AdminTimeout, "ADMIN_TIMEOUT")
+	setDuration(&c.MongoDB.MaxQueryTime, "MAX_QUERY_TIME")
+
+	setList(&c.CORS.AllowedOrigins, "CORS_ORIGINS")
+	setList(&c.CORS.AllowedMethods, "CORS_METHODS")
+	setList(&c.CORS.AllowedHeaders, "CORS_HEADERS")
+
+	setInt64(&c.Pagination.DefaultPageSize, "DEFAULT_PAGE_SIZE")
+	setInt64(&c.Pagination.MaxPageSize, "MAX_PAGE_SIZE")
+
+	setString(&c.Logging.Level, "LOG_LEVEL")
+	setString(&c.Logging.Format, "LOG_FORMAT")
+
+	setInt64(&c.Bulk.MaxDocuments, "BULK_MAX_DOCUMENTS")
+	setDuration(&c.Bulk.PreviewTTL, "BULK_PREVIEW_TTL")
+
+	setBool(&c.Trash.Enabled, "TRASH_ENABLED")
+	setString(&c.Trash.Database, "TRASH_DATABASE")
+	setInt(&c.Trash.RetentionDays, "TRASH_RETENTION_DAYS")
+	setDuration(&c.Trash.SweepInterval, "TRASH_SWEEP_INTERVAL")
+
+	setBool(&c.Revisions.Enabled, "REVISIONS_ENABLED")
+	setList(&c.Revisions.Collections, "REVISIONS_COLLECTIONS")
+	setInt64(&c.Revisions.MaxPerDocument, "REVISIONS_MAX_PER_DOCUMENT")
+
+	setBool(&c.Commands.Enabled, "COMMANDS_ENABLED")
+	setString(&c.Commands.DefaultRole, "COMMANDS_DEFAULT_ROLE")
+
+	setBool(&c.Shell.Enabled, "SHELL_ENABLED")
+	setDuration(&c.Shell.Timeout, "SHELL_TIMEOUT")
+	setList(&c.Shell.Roles, "SHELL_ROLES")
+}
+
+// validate 校验配置
+func (c *Config) validate() error {
+	for _, proxy := range c.Server.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			return fmt.Errorf("server.trusted_proxies: invalid IP or CIDR %q", proxy)
+		}
+	}
+	if c.Pagination.MaxPageSize < 1 {
+		return fmt.Errorf("pagination.max_page_size must be positive")
+	}
+	if c.Pagination.DefaultPageSize < 1 || c.Pagination.DefaultPageSize > c.Pagination.MaxPageSize {
+		return fmt.Errorf("pagination.default_page_size must be between 1 and max_page_size")
+	}
+	if c.MongoDB.MinPoolSize > c.MongoDB.MaxPoolSize && c.MongoDB.MaxPoolSize != 0 {
+		return fmt.Errorf("mongodb.min_pool_size must not exceed max_pool_size")
+	}
+	if c.Bulk.PreviewTTL <= 0 {
+		return fmt.Errorf("bulk.preview_ttl must be positive")
+	}
+	if c.Trash.Enabled {
+		if c.Trash.Database == "" {
+			return fmt.Errorf("trash.database must not be empty")
+		}
+		if c.Trash.RetentionDays < 0 {
+			return fmt.Errorf("trash.retention_days must not be negative")
+		}
+		if c.Trash.SweepInterval <= 0 {
+			return fmt.Errorf("trash.sweep_interval must be positive")
+		}
+	}
+	if c.Revisions.MaxPerDocument < 0 {
+		return fmt.Errorf("revisions.max_per_document must not be negative")
+	}
+	if c.Commands.Enabled {
+		if _, exists := c.Commands.Roles[c.Commands.DefaultRole]; !exists {
+			return fmt.Errorf("commands.default_role %q is not defined in commands.roles", c.Commands.DefaultRole)
+		}
+		for id, role := range c.Commands.Connections {
+			if _, exists := c.Commands.Roles[role]; !exists {
+				return fmt.Errorf("commands.connections[%q]: role %q is not defined in commands.roles", id, role)
+			}
+		}
+	}
+	if c.Shell.Enabled && c.Shell.Timeout <= 0 {
+		return fmt.Errorf("shell.timeout must be positive")
+	}
+	switch strings.ToLower(c.Logging.Format) {
+	case "json", "text":
+	default:
+		return fmt.Errorf("unsupported logging.format %q", c.Logging.Format)
+	}
+	return nil
+}
+
+func setString(dst *string, key string) {
+	if value := os.Getenv(key); value != "" {
+		*dst = value
+	}
+}
+
+func setList(dst *[]string, key string) {
+	value := os.Getenv(key)
+	if value == "" {
+		return
+	}
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*dst = items
+}
+
+func setBool(dst *bool, key string) {
+	value := os.Getenv(key)
+	if value == "" {
+		return
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Invalid %s %q, ignored", key, value)
+		return
+	}
+	*dst = b
+}
+
+func setInt(dst *int, key string) {
+	var v int64
+	if setInt64(&v, key) {
+		*dst = int(v)
+	}
+}
+
+func setInt64(dst *int64, key string) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return false
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		log.Printf("Invalid %s %q, ignored", key, value)
+		return false
+	}
+	*dst = n
+	return true
+}
+
+func setUint(dst *uint64, key string) {
+	value := os.Getenv(key)
+	if value == "" {
+		return
+	}
+	n, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		log.Printf("Invalid %s %q, ignored", key, value)
+		return
+	}
+	*dst = n
+}
+
+// setDuration 读取时间间隔类型的环境变量，例如 "30s"、"2m"
+func setDuration(dst *time.Duration, key string) {
+	value := os.Getenv(key)
+	if value == "" {
+		return
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid %s %q, ignored", key, value)
+		return
+	}
+	*dst = d
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// NewLogger 根据日志配置创建日志记录器
+func (l LoggingConfig) NewLogger() *slog.Logger {
+	opts := &slog.HandlerOptions{Level: l.level()}
+
+	var handler slog.Handler
+	if strings.EqualFold(l.Format, "json") {
+		handler = slog.NewJSONHandler(os.Stdout, opts)
+	} else {
+		handler = slog.NewTextHandler(os.Stdout, opts)
+	}
+	return slog.New(handler)
+}
+
+// IsDebug 是否为调试级别
+func (l LoggingConfig) IsDebug() bool {
+	return l.level() == slog.LevelDebug
+}
+
+func (l LoggingConfig) level() slog.Level {
+	switch strings.ToLower(l.Level) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
@@ -0,0 +1,207 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// bulkBatchSize 批量操作每批处理的文档数，批次之间检查取消
+const bulkBatchSize = 1000
+
+// ErrBulkLimit 执行时匹配的文档数超过上限（预览之后可能有新文档匹配）
+var ErrBulkLimit = errors.New("bulk operation exceeds the document limit")
+
+// BulkPreview 批量更新/删除的预览结果
+type BulkPreview struct {
+	Matched         int64        `json:"matched"`
+	Samples         []BulkSample `json:"samples"`
+	DiffUnavailable string       `json:"diffUnavailable,omitempty"`
+}
+
+// BulkSample 受影响文档的示例，更新操作附带更新后的文档和字段差异
+type BulkSample struct {
+	Before  bson.D        `json:"-"`
+	After   bson.D        `json:"-"`
+	Changes []FieldChange `json:"changes,omitempty"`
+}
+
+// MarshalJSON 文档以relaxed Extended JSON输出
+func (s BulkSample) MarshalJSON() ([]byte, error) {
+	out := struct {
+		Before  json.RawMessage `json:"before"`
+		After   json.RawMessage `json:"after,omitempty"`
+		Changes []FieldChange   `json:"changes,omitempty"`
+	}{Before: ExtJSONValue(s.Before), Changes: s.Changes}
+	if s.After != nil {
+		out.After = ExtJSONValue(s.After)
+	}
+	return json.Marshal(out)
+}
+
+// PreviewBulk 统计过滤条件匹配的文档数并返回示例；update不为空时在本地模拟更新并给出差异。
+// update为聚合管道时无法模拟，只返回匹配数和原文档
+func (s *Service) PreviewBulk(ctx context.Context, dbName, collectionName string, filter bson.D, update interface{}, sampleSize int64) (*BulkPreview, error) {
+	ctx, cancel := s.withTimeout(ctx, s.timeouts.Read)
+	defer cancel()
+
+	collection := s.client.Database(dbName).Collection(collectionName)
+
+	matched, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := collection.Find(ctx, filter, options.Find().SetLimit(sampleSize))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	preview := &BulkPreview{Matched: matched, Samples: []BulkSample{}}
+	updateDoc, isDoc := update.(bson.D)
+	if update != nil && !isDoc {
+		preview.DiffUnavailable = "pipeline updates cannot be previewed"
+	}
+
+	for cursor.Next(ctx) {
+		var doc bson.D
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+		sample := BulkSample{Before: doc}
+		if isDoc && preview.DiffUnavailable == "" {
+			after, err := ApplyUpdate(doc, updateDoc)
+			switch {
+			case errors.Is(err, ErrUnsupportedUpdate):
+				preview.DiffUnavailable = err.Error()
+			case err != nil:
+				return nil, err
+			default:
+				sample.After = after
+				sample.Changes = DiffDocuments(doc, after)
+			}
+		}
+		preview.Samples = append(preview.Samples, sample)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	// 部分示例模拟失败时，不展示不完整的差异
+	if preview.DiffUnavailable != "" {
+		for i := range preview.Samples {
+			preview.Samples[i].After = nil
+			preview.Samples[i].Changes = nil
+		}
+	}
+	return preview, nil
+}
+
+// BulkUpdate 按批次执行updateMany，progress在每批完成后回调已处理数量和总数；
+// 匹配的文档超过maxDocuments（大于0时）时不做任何修改并返回ErrBulkLimit
+func (s *Service) BulkUpdate(ctx context.Context, dbName, collectionName string, filter bson.D, update interface{}, maxDocuments int64, progress func(done, total int64)) (int64, error) {
+	collection := s.client.Database(dbName).Collection(collectionName)
+
+	var modified int64
+	err := s.eachBatch(ctx, collection, filter, maxDocuments, progress, func(ctx context.Context, batchFilter bson.D) (int64, error) {
+		result, err := collection.UpdateMany(ctx, batchFilter, update)
+		if err != nil {
+			return 0, err
+		}
+		modified += result.ModifiedCount
+		return result.MatchedCount, nil
+	})
+	return modified, err
+}
+
+// BulkDelete 按批次执行deleteMany，progress在每批完成后回调已处理数量和总数；
+// 匹配的文档超过maxDocuments（大于0时）时不做任何删除并返回ErrBulkLimit
+func (s *Service) BulkDelete(ctx context.Context, dbName, collectionName string, filter bson.D, maxDocuments int64, progress func(done, total int64)) (int64, error) {
+	collection := s.client.Database(dbName).Collection(collectionName)
+
+	var deleted int64
+	err := s.eachBatch(ctx, collection, filter, maxDocuments, progress, func(ctx context.Context, batchFilter bson.D) (int64, error) {
+		result, err := collection.DeleteMany(ctx, batchFilter)
+		if err != nil {
+			return 0, err
+		}
+		deleted += result.DeletedCount
+		return result.DeletedCount, nil
+	})
+	return deleted, err
+}
+
+// eachBatch 先收集匹配文档的_id，再分批以 {$and: [filter, {_id: {$in: ids}}]} 执行操作，
+// 使长时间运行的批量操作可以汇报进度并在批次之间响应取消
+func (s *Service) eachBatch(ctx context.Context, collection *mongo.Collection, filter bson.D, maxDocuments int64, progress func(done, total int64), fn func(ctx context.Context, batchFilter bson.D) (int64, error)) error {
+	ids, err := matchingIDs(ctx, collection, filter, maxDocuments)
+	if err != nil {
+		return err
+	}
+
+	total := int64(len(ids))
+	var done int64
+	if progress != nil {
+		progress(done, total)
+	}
+
+	for start := 0; start < len(ids); start += bulkBatchSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		end := start + bulkBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		batchFilter := bson.D{{Key: "$and", Value: bson.A{
+			filter,
+			bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: ids[start:end]}}}},
+		}}}
+
+		batchCtx, cancel := s.withTimeout(ctx, s.timeouts.Write)
+		_, err := fn(batchCtx, batchFilter)
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		done += int64(end - start)
+		if progress != nil {
+			progress(done, total)
+		}
+	}
+	return nil
+}
+
+// matchingIDs 获取匹配文档的_id列表，最多读取maxDocuments+1个以判断是否超过上限。
+// 在后台任务中运行，只受任务的ctx控制，不使用读超时
+func matchingIDs(ctx context.Context, collection *mongo.Collection, filter bson.D, maxDocuments int64) ([]interface{}, error) {
+	findOptions := options.Find().SetProjection(bson.M{"_id": 1})
+	if maxDocuments > 0 {
+		findOptions.SetLimit(maxDocuments + 1)
+	}
+	cursor, err := collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var ids []interface{}
+	for cursor.Next(ctx) {
+		ids = append(ids, cursor.Current.Lookup("_id"))
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+	if maxDocuments > 0 && int64(len(ids)) > maxDocuments {
+		return nil, fmt.Errorf("%w: more than %d documents match", ErrBulkLimit, maxDocuments)
+	}
+	return ids, nil
+}
@@ -0,0 +1,46 @@
+package database
+
+import (
+	"context"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// CommandInfo 服务端支持的命令，来自listCommands
+type CommandInfo struct {
+	Name      string `json:"name"`
+	Help      string `json:"help"`
+	AdminOnly bool   `json:"adminOnly"`
+}
+
+// RunCommand 在数据库上执行任意命令，返回服务端的原始响应；命令失败时返回mongo.CommandError
+func (s *Service) RunCommand(ctx context.Context, dbName string, command bson.D) (bson.Raw, error) {
+	ctx, cancel := s.withTimeout(ctx, s.timeouts.Admin)
+	defer cancel()
+
+	return s.client.Database(dbName).RunCommand(ctx, command).Raw()
+}
+
+// ListCommands 按名称排序列出服务端支持的命令
+func (s *Service) ListCommands(ctx context.Context) ([]CommandInfo, error) {
+	ctx, cancel := s.withTimeout(ctx, s.timeouts.Read)
+	defer cancel()
+
+	var result struct {
+		Commands map[string]struct {
+			Help      string `bson:"help"`
+			AdminOnly bool   `bson:"adminOnly"`
+		} `bson:"commands"`
+	}
+	if err := s.client.Database("admin").RunCommand(ctx, bson.D{{Key: "listCommands", Value: 1}}).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	commands := make([]CommandInfo, 0, len(result.Commands))
+	for name, info := range result.Commands {
+		commands = append(commands, CommandInfo{Name: name, Help: info.Help, AdminOnly: info.AdminOnly})
+	}
+	sort.Slice(commands, func(i, j int) bool { return commands[i].Name < commands[j].Name })
+	return commands, nil
+}
@@ -11,14 +11,43 @@ import (
 )
 
 type Service struct {
-	client *mongo.Client
+	client   *mongo.Client
+	timeouts Timeouts
 }
 
-func Connect(uri string) (*mongo.Client, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// Timeouts 各类操作的超时时间，0表示只受请求上下文控制
+type Timeouts struct {
+	Read  time.Duration // 查询类操作
+	Write time.Duration // 文档写入
+	Admin time.Duration // 创建/删除数据库和集合、服务器统计
+	// MaxQuery 客户端指定的maxTimeMS的上限，0表示不允许超过读操作超时
+	MaxQuery time.Duration
+}
+
+// ConnectOptions 客户端连接参数，零值字段使用驱动默认值
+type ConnectOptions struct {
+	Timeout     time.Duration
+	MaxPoolSize uint64
+	MinPoolSize uint64
+}
+
+func Connect(uri string, opts ConnectOptions) (*mongo.Client, error) {
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	clientOpts := options.Client().ApplyURI(uri).SetConnectTimeout(timeout)
+	if opts.MaxPoolSize > 0 {
+		clientOpts.SetMaxPoolSize(opts.MaxPoolSize)
+	}
+	if opts.MinPoolSize > 0 {
+		clientOpts.SetMinPoolSize(opts.MinPoolSize)
+	}
+
+	client, err := mongo.Connect(ctx, clientOpts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to MongoDB: %w", err)
 	}
@@ -29,25 +58,37 @@ func Connect(uri string) (*mongo.Client, error) {
 		return nil, fmt.Errorf("failed to ping MongoDB: %w", err)
 	}
 
-	fmt.Println("Connected to MongoDB successfully!")
 	return client, nil
 }
 
-func NewService(client *mongo.Client) *Service {
-	return &Service{client: client}
+func NewService(client *mongo.Client, timeouts Timeouts) *Service {
+	return &Service{client: client, timeouts: timeouts}
+}
+
+// withTimeout 在请求上下文的基础上附加操作超时，客户端断开时操作同样会被取消
+func (s *Service) withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// Collection 返回驱动的集合对象，供脚本控制台等需要直接调用驱动的场景使用，调用方自行控制超时
+func (s *Service) Collection(dbName, collectionName string) *mongo.Collection {
+	return s.client.Database(dbName).Collection(collectionName)
 }
 
 // 获取所有数据库
-func (s *Service) GetDatabases() ([]string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (s *Service) GetDatabases(ctx context.Context) ([]string, error) {
+	ctx, cancel := s.withTimeout(ctx, s.timeouts.Read)
 	defer cancel()
 
 	return s.client.ListDatabaseNames(ctx, bson.M{})
 }
 
 // 获取数据库信息
-func (s *Service) GetDatabase(dbName string) (*DatabaseInfo, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (s *Service) GetDatabase(ctx context.Context, dbName string) (*DatabaseInfo, error) {
+	ctx, cancel := s.withTimeout(ctx, s.timeouts.Read)
 	defer cancel()
 
 	db := s.client.Database(dbName)
@@ -73,8 +114,8 @@ func (s *Service) GetDatabase(dbName string) (*DatabaseInfo, error) {
 }
 
 // 创建数据库
-func (s *Service) CreateDatabase(dbName string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (s *Service) CreateDatabase(ctx context.Context, dbName string) error {
+	ctx, cancel := s.withTimeout(ctx, s.timeouts.Admin)
 	defer cancel()
 
 	// 创建一个简单的集合来创建数据库
@@ -86,16 +127,16 @@ func (s *Service) CreateDatabase(dbName string) error {
 }
 
 // 删除数据库
-func (s *Service) DeleteDatabase(dbName string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func (s *Service) DeleteDatabase(ctx context.Context, dbName string) error {
+	ctx, cancel := s.withTimeout(ctx, s.timeouts.Admin)
 	defer cancel()
 
 	return s.client.Database(dbName).Drop(ctx)
 }
 
 // 获取数据库的所有集合
-func (s *Service) GetCollections(dbName string) ([]string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (s *Service) GetCollections(ctx context.Context, dbName string) ([]string, error) {
+	ctx, cancel := s.withTimeout(ctx, s.timeouts.Read)
 	defer cancel()
 
 	db := s.client.Database(dbName)
@@ -103,8 +144,8 @@ func (s *Service) GetCollections(dbName string) ([]string, error) {
 }
 
 // 创建集合
-func (s *Service) CreateCollection(dbName, collectionName string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (s *Service) CreateCollection(ctx context.Context, dbName, collectionName string) error {
+	ctx, cancel := s.withTimeout(ctx, s.timeouts.Admin)
 	defer cancel()
 
 	db := s.client.Database(dbName)
@@ -112,8 +153,8 @@ func (s *Service) CreateCollection(dbName, collectionName string) error {
 }
 
 // 删除集合
-func (s *Service) DeleteCollection(dbName, collectionName string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (s *Service) DeleteCollection(ctx context.Context, dbName, collectionName string) error {
+	ctx, cancel := s.withTimeout(ctx, s.timeouts.Admin)
 	defer cancel()
 
 	db := s.client.Database(dbName)
@@ -121,64 +162,62 @@ func (s *Service) DeleteCollection(dbName, collectionName string) error {
 }
 
 // 获取集合中的文档
-func (s *Service) GetDocuments(dbName, collectionName string, page, limit int64) (*DocumentsResponse, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (s *Service) GetDocuments(ctx context.Context, dbName, collectionName string, page, limit int64, maxTime time.Duration) (*DocumentsResponse, error) {
+	return s.QueryDocuments(ctx, dbName, collectionName, Query{}, page, limit, maxTime)
+}
+
+// 创建文档
+func (s *Service) CreateDocument(ctx context.Context, dbName, collectionName string, document bson.D) (interface{}, error) {
+	ctx, cancel := s.withTimeout(ctx, s.timeouts.Write)
 	defer cancel()
 
 	db := s.client.Database(dbName)
 	collection := db.Collection(collectionName)
 
-	// 计算跳过的文档数
-	skip := (page - 1) * limit
-
-	// 获取总数
-	total, err := collection.CountDocuments(ctx, bson.M{})
+	result, err := collection.InsertOne(ctx, document)
 	if err != nil {
 		return nil, err
 	}
 
-	// 获取文档
-	cursor, err := collection.Find(ctx, bson.M{}, options.Find().
-		SetSkip(skip).
-		SetLimit(limit).
-		SetSort(bson.M{"_id": -1}))
-	if err != nil {
-		return nil, err
-	}
-	defer cursor.Close(ctx)
-
-	var documents []map[string]interface{}
-	if err := cursor.All(ctx, &documents); err != nil {
-		return nil, err
-	}
-
-	return &DocumentsResponse{
-		Documents: documents,
-		Total:     total,
-		Page:      page,
-		Limit:     limit,
-	}, nil
+	return result.InsertedID, nil
 }
 
-// 创建文档
-func (s *Service) CreateDocument(dbName, collectionName string, document map[string]interface{}) (interface{}, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+// ReplaceDocument 整体替换文档；document中的_id可以省略，若存在则必须与id一致。
+// original不为空时只有当前文档与original一致才会替换，否则返回ErrDocumentModified
+func (s *Service) ReplaceDocument(ctx context.Context, dbName, collectionName, id string, document, original bson.D) error {
+	ctx, cancel := s.withTimeout(ctx, s.timeouts.Write)
 	defer cancel()
 
 	db := s.client.Database(dbName)
 	collection := db.Collection(collectionName)
 
-	result, err := collection.InsertOne(ctx, document)
+	objectID, err := toObjectID(id)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return result.InsertedID, nil
+	replacement := make(bson.D, 0, len(document))
+	for _, e := range document {
+		if e.Key != "_id" {
+			replacement = append(replacement, e)
+			continue
+		}
+		if !ValuesEqual(e.Value, objectID) && e.Value != id {
+			return ErrImmutableID
+		}
+	}
+
+	result, err := collection.ReplaceOne(ctx, documentFilter(objectID, original), replacement)
+	if err != nil {
+		return err
+	}
+	return matchError(result.MatchedCount, original)
 }
 
-// 更新文档
-func (s *Service) UpdateDocument(dbName, collectionName, id string, document map[string]interface{}) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+// UpdateDocument 使用更新运算符或聚合管道更新文档，arrayFilters用于 $[<identifier>] 位置运算符。
+// original的含义同ReplaceDocument
+func (s *Service) UpdateDocument(ctx context.Context, dbName, collectionName, id string, update interface{}, arrayFilters []interface{}, original bson.D) error {
+	ctx, cancel := s.withTimeout(ctx, s.timeouts.Write)
 	defer cancel()
 
 	db := s.client.Database(dbName)
@@ -189,13 +228,21 @@ func (s *Service) UpdateDocument(dbName, collectionName, id string, document map
 		return err
 	}
 
-	_, err = collection.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{"$set": document})
-	return err
+	opts := options.Update()
+	if len(arrayFilters) > 0 {
+		opts.SetArrayFilters(options.ArrayFilters{Filters: arrayFilters})
+	}
+
+	result, err := collection.UpdateOne(ctx, documentFilter(objectID, original), update, opts)
+	if err != nil {
+		return err
+	}
+	return matchError(result.MatchedCount, original)
 }
 
-// 删除文档
-func (s *Service) DeleteDocument(dbName, collectionName, id string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+// 删除文档，original的含义同ReplaceDocument
+func (s *Service) DeleteDocument(ctx context.Context, dbName, collectionName, id string, original bson.D) error {
+	ctx, cancel := s.withTimeout(ctx, s.timeouts.Write)
 	defer cancel()
 
 	db := s.client.Database(dbName)
@@ -206,13 +253,17 @@ func (s *Service) DeleteDocument(dbName, collectionName, id string) error {
 		return err
 	}
 
-	_, err = collection.DeleteOne(ctx, bson.M{"_id": objectID})
-	return err
+	result, err := collection.DeleteOne(ctx, documentFilter(objectID, original))
+	if err != nil {
+		return err
+	}
+	return matchError(result.DeletedCount, original)
 }
 
-// 查询文档
-func (s *Service) QueryDocuments(dbName, collectionName string, query map[string]interface{}, page, limit int64) (*DocumentsResponse, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+// 查询文档，maxTime为0时使用读操作超时作为服务端的maxTimeMS；未指定排序时按_id倒序
+func (s *Service) QueryDocuments(ctx context.Context, dbName, collectionName string, query Query, page, limit int64, maxTime time.Duration) (*DocumentsResponse, error) {
+	maxTime = s.queryMaxTime(maxTime)
+	ctx, cancel := s.withTimeout(ctx, clientTimeout(maxTime))
 	defer cancel()
 
 	db := s.client.Database(dbName)
@@ -221,17 +272,43 @@ func (s *Service) QueryDocuments(dbName, collectionName string, query map[string
 	// 计算跳过的文档数
 	skip := (page - 1) * limit
 
+	if len(query.Pipeline) > 0 {
+		return s.aggregateDocuments(ctx, collection, query.Pipeline, page, limit, maxTime)
+	}
+
+	filter := query.Filter
+	if filter == nil {
+		filter = bson.D{}
+	}
+	// $near 查询的结果已按距离排序，不再使用默认排序
+	sort := query.Sort
+	if len(sort) == 0 && !usesNear(filter) {
+		sort = bson.D{{Key: "_id", Value: -1}}
+	}
+
+	countOpts := options.Count()
+	findOpts := options.Find().
+		SetSkip(skip).
+		SetLimit(limit)
+	if len(sort) > 0 {
+		findOpts.SetSort(sort)
+	}
+	if len(query.Projection) > 0 {
+		findOpts.SetProjection(query.Projection)
+	}
+	if maxTime > 0 {
+		countOpts.SetMaxTime(maxTime)
+		findOpts.SetMaxTime(maxTime)
+	}
+
 	// 获取总数
-	total, err := collection.CountDocuments(ctx, query)
+	total, err := collection.CountDocuments(ctx, countFilter(filter), countOpts)
 	if err != nil {
 		return nil, err
 	}
 
 	// 获取文档
-	cursor, err := collection.Find(ctx, query, options.Find().
-		SetSkip(skip).
-		SetLimit(limit).
-		SetSort(bson.M{"_id": -1}))
+	cursor, err := collection.Find(ctx, filter, findOpts)
 	if err != nil {
 		return nil, err
 	}
@@ -250,9 +327,78 @@ func (s *Service) QueryDocuments(dbName, collectionName string, query map[string
 	}, nil
 }
 
+// aggregateDocuments 按聚合管道查询，分别追加 $count 和 $skip/$limit 阶段获取总数和当前页
+func (s *Service) aggregateDocuments(ctx context.Context, collection *mongo.Collection, pipeline []bson.D, page, limit int64, maxTime time.Duration) (*DocumentsResponse, error) {
+	opts := options.Aggregate()
+	if maxTime > 0 {
+		opts.SetMaxTime(maxTime)
+	}
+
+	countPipeline := append(append([]bson.D{}, pipeline...), bson.D{{Key: "$count", Value: "total"}})
+	countCursor, err := collection.Aggregate(ctx, countPipeline, opts)
+	if err != nil {
+		return nil, err
+	}
+	var counts []struct {
+		Total int64 `bson:"total"`
+	}
+	if err := countCursor.All(ctx, &counts); err != nil {
+		return nil, err
+	}
+	var total int64
+	if len(counts) > 0 {
+		total = counts[0].Total
+	}
+
+	pagePipeline := append(append([]bson.D{}, pipeline...),
+		bson.D{{Key: "$skip", Value: (page - 1) * limit}},
+		bson.D{{Key: "$limit", Value: limit}},
+	)
+	cursor, err := collection.Aggregate(ctx, pagePipeline, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var documents []map[string]interface{}
+	if err := cursor.All(ctx, &documents); err != nil {
+		return nil, err
+	}
+
+	return &DocumentsResponse{
+		Documents: documents,
+		Total:     total,
+		Page:      page,
+		Limit:     limit,
+	}, nil
+}
+
+// queryMaxTime 客户端指定的maxTime不超过MaxQuery（未配置时为读操作超时），未指定时使用读操作超时
+func (s *Service) queryMaxTime(maxTime time.Duration) time.Duration {
+	if maxTime <= 0 {
+		return s.timeouts.Read
+	}
+	limit := s.timeouts.MaxQuery
+	if limit <= 0 {
+		limit = s.timeouts.Read
+	}
+	if limit > 0 && maxTime > limit {
+		return limit
+	}
+	return maxTime
+}
+
+// clientTimeout 客户端超时比服务端maxTimeMS多留一秒，以便返回服务端的超时错误
+func clientTimeout(maxTime time.Duration) time.Duration {
+	if maxTime <= 0 {
+		return 0
+	}
+	return maxTime + time.Second
+}
+
 // 获取统计信息
-func (s *Service) GetStats() (*ServerStats, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (s *Service) GetStats(ctx context.Context) (*ServerStats, error) {
+	ctx, cancel := s.withTimeout(ctx, s.timeouts.Admin)
 	defer cancel()
 
 	var stats ServerStats
@@ -271,8 +417,8 @@ func (s *Service) GetStats() (*ServerStats, error) {
 }
 
 // GetDocument 获取单个文档
-func (s *Service) GetDocument(dbName, collectionName, id string) (map[string]interface{}, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (s *Service) GetDocument(ctx context.Context, dbName, collectionName, id string) (bson.D, error) {
+	ctx, cancel := s.withTimeout(ctx, s.timeouts.Read)
 	defer cancel()
 
 	db := s.client.Database(dbName)
@@ -283,11 +429,11 @@ func (s *Service) GetDocument(dbName, collectionName, id string) (map[string]int
 		return nil, err
 	}
 
-	var document map[string]interface{}
+	var document bson.D
 	err = collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&document)
 	if err != nil {
 		return nil, err
 	}
 
-	return formatDocument(document), nil
+	return document, nil
 }
\ No newline at end of file
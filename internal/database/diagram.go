@@ -0,0 +1,239 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// DefaultDiagramSampleSize 生成关系图时每个集合默认采样的文档数
+const DefaultDiagramSampleSize = 100
+
+// Diagram 数据库的实体关系图：集合为节点，引用字段为边
+type Diagram struct {
+	Database    string              `json:"database"`
+	SampleSize  int64               `json:"sampleSize"`
+	Collections []DiagramCollection `json:"collections"`
+	Relations   []DiagramRelation   `json:"relations"`
+}
+
+// DiagramCollection 关系图中的集合，External表示其他数据库中被引用的集合，名称为 db.collection
+type DiagramCollection struct {
+	Name     string         `json:"name"`
+	Sampled  int64          `json:"sampled"`
+	Fields   []DiagramField `json:"fields"`
+	External bool           `json:"external,omitempty"`
+}
+
+// DiagramField 集合的顶层字段及其出现最多的类型
+type DiagramField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// DiagramRelation From 集合的 Field 字段引用 To 集合的文档；Many表示字段在数组中（一个文档引用多个），
+// Count为采样文档中该引用出现的次数
+type DiagramRelation struct {
+	From   string `json:"from"`
+	Field  string `json:"field"`
+	To     string `json:"to"`
+	Source string `json:"source"`
+	Many   bool   `json:"many"`
+	Count  int64  `json:"count"`
+}
+
+// RelationshipDiagram 采样数据库中的各集合，根据DBRef、引用映射（以集合名和字段路径为键）和ObjectId字段推断集合间的引用关系。
+// 按字段名推断时，其他集合中同名字段引用的集合会最先用本集合的候选值查找，命中时不再探测其他集合
+func (s *Service) RelationshipDiagram(ctx context.Context, dbName string, sampleSize int64, mappings map[string]map[string]ReferenceTarget) (*Diagram, error) {
+	ctx, cancel := s.withTimeout(ctx, s.timeouts.Admin)
+	defer cancel()
+
+	if sampleSize <= 0 {
+		sampleSize = DefaultDiagramSampleSize
+	}
+	sampleSize = normalizeSampleSize(sampleSize)
+
+	collections, err := s.probeCollections(ctx, dbName)
+	if err != nil {
+		return nil, err
+	}
+	if len(collections) > maxProbeCollections {
+		collections = collections[:maxProbeCollections]
+	}
+
+	diagram := &Diagram{Database: dbName, SampleSize: sampleSize, Collections: []DiagramCollection{}, Relations: []DiagramRelation{}}
+	relations := make(map[DiagramRelation]*DiagramRelation)
+	var order []DiagramRelation
+	external := make(map[string]bool)
+	probed := make(map[string]*ReferenceTarget)
+
+	for _, name := range collections {
+		docs, err := s.sampleDocuments(ctx, dbName, name, sampleSize)
+		if err != nil {
+			return nil, err
+		}
+
+		stats := make(map[string]*FieldStats)
+		var candidates []*referenceCandidate
+		for i, raw := range docs {
+			walkDocument(raw, "", 0, stats, make(map[string]bool))
+			var doc bson.D
+			if err := bson.Unmarshal(raw, &doc); err != nil {
+				return nil, err
+			}
+			candidates = append(candidates, referenceCandidates(doc, i, dbName, mappings[name])...)
+		}
+		if err := s.inferReferenceTargets(ctx, dbName, collections, candidates, probed); err != nil {
+			return nil, err
+		}
+
+		collection := DiagramCollection{Name: name, Sampled: int64(len(docs)), Fields: []DiagramField{}}
+		for path, field := range stats {
+			if field.Depth == 0 {
+				collection.Fields = append(collection.Fields, DiagramField{Name: path, Type: field.DominantType()})
+			}
+		}
+		sort.Slice(collection.Fields, func(i, k int) bool {
+			a, b := collection.Fields[i].Name, collection.Fields[k].Name
+			return a == "_id" || (b != "_id" && a < b)
+		})
+		diagram.Collections = append(diagram.Collections, collection)
+
+		for _, candidate := range candidates {
+			if candidate.target == nil {
+				continue
+			}
+			to := candidate.target.Collection
+			if candidate.target.Database != dbName {
+				to = candidate.target.Database + "." + candidate.target.Collection
+				external[to] = true
+			}
+			key := DiagramRelation{
+				From:   name,
+				Field:  strings.TrimSuffix(candidate.field, ".$id"),
+				To:     to,
+				Source: candidate.source,
+			}
+			relation := relations[key]
+			if relation == nil {
+				relation = &DiagramRelation{From: key.From, Field: key.Field, To: key.To, Source: key.Source}
+				relations[key] = relation
+				order = append(order, key)
+			}
+			relation.Count++
+			if strings.Count(candidate.path, ".") != strings.Count(candidate.field, ".") {
+				relation.Many = true
+			}
+		}
+	}
+
+	for _, key := range order {
+		diagram.Relations = append(diagram.Relations, *relations[key])
+	}
+	names := make([]string, 0, len(external))
+	for name := range external {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		diagram.Collections = append(diagram.Collections, DiagramCollection{Name: name, Fields: []DiagramField{}, External: true})
+	}
+	return diagram, nil
+}
+
+// DOT 以Graphviz DOT格式输出，集合为record节点，数组中的引用以crow箭头表示
+func (d *Diagram) DOT() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %s {\n", dotQuote(d.Database))
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=record, fontname=\"Helvetica\", fontsize=10];\n")
+	b.WriteString("  edge [fontname=\"Helvetica\", fontsize=9];\n\n")
+	for _, collection := range d.Collections {
+		var fields strings.Builder
+		for _, field := range collection.Fields {
+			fields.WriteString(dotRecordEscape(field.Name+" : "+field.Type) + "\\l")
+		}
+		label := "{" + dotRecordEscape(collection.Name) + "|" + fields.String() + "}"
+		attrs := ""
+		if collection.External {
+			attrs = ", style=dashed"
+		}
+		fmt.Fprintf(&b, "  %s [label=%s%s];\n", dotQuote(collection.Name), dotQuote(label), attrs)
+	}
+	if len(d.Relations) > 0 {
+		b.WriteString("\n")
+	}
+	for _, relation := range d.Relations {
+		attrs := "label=" + dotQuote(relation.Field)
+		if relation.Many {
+			attrs += ", arrowtail=crow, dir=both"
+		}
+		if relation.Source == ReferenceHeuristic {
+			attrs += ", style=dashed"
+		}
+		fmt.Fprintf(&b, "  %s -> %s [%s];\n", dotQuote(relation.From), dotQuote(relation.To), attrs)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// dotQuote DOT中带引号的字符串，其中的反斜杠转义（如标签中的 \l）保持原样
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+// dotRecordEscape 转义record标签中有特殊含义的字符
+func dotRecordEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "{", `\{`, "}", `\}`, "|", `\|`, "<", `\<`, ">", `\>`).Replace(s)
+}
+
+// mermaidUnsafe Mermaid实体名和属性中不允许的字符
+var mermaidUnsafe = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// Mermaid 以Mermaid erDiagram格式输出。实体名中的点等字符替换为下划线，替换后重名的集合加上数字后缀；
+// 单值引用表示为被引用的集合对应多个引用方，数组中的引用表示为多对多
+func (d *Diagram) Mermaid() string {
+	entities := make(map[string]string, len(d.Collections))
+	used := make(map[string]bool, len(d.Collections))
+	for _, collection := range d.Collections {
+		base := mermaidUnsafe.ReplaceAllString(collection.Name, "_")
+		name := base
+		for i := 2; used[name]; i++ {
+			name = fmt.Sprintf("%s_%d", base, i)
+		}
+		used[name] = true
+		entities[collection.Name] = name
+	}
+	entity := func(name string) string {
+		if e, ok := entities[name]; ok {
+			return e
+		}
+		return mermaidUnsafe.ReplaceAllString(name, "_")
+	}
+	var b strings.Builder
+	b.WriteString("erDiagram\n")
+	for _, collection := range d.Collections {
+		fmt.Fprintf(&b, "    %s {\n", entity(collection.Name))
+		for _, field := range collection.Fields {
+			name := mermaidUnsafe.ReplaceAllString(field.Name, "_")
+			if name == "" || name[0] == '-' {
+				name = "_" + name
+			}
+			fmt.Fprintf(&b, "        %s %s\n", mermaidUnsafe.ReplaceAllString(field.Type, "_"), name)
+		}
+		b.WriteString("    }\n")
+	}
+	for _, relation := range d.Relations {
+		label := strings.ReplaceAll(relation.Field, `"`, "'")
+		if relation.Many {
+			fmt.Fprintf(&b, "    %s }o--o{ %s : \"%s\"\n", entity(relation.From), entity(relation.To), label)
+		} else {
+			fmt.Fprintf(&b, "    %s ||--o{ %s : \"%s\"\n", entity(relation.To), entity(relation.From), label)
+		}
+	}
+	return b.String()
+}
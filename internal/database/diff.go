@@ -0,0 +1,171 @@
+package database
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// 字段变更类型
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// FieldChange 字段级别的变更
+type FieldChange struct {
+	Path string      `json:"path"`
+	Op   string      `json:"op"`
+	Old  interface{} `json:"-"`
+	New  interface{} `json:"-"`
+}
+
+// MarshalJSON 旧值和新值以relaxed Extended JSON输出，保留类型信息
+func (c FieldChange) MarshalJSON() ([]byte, error) {
+	out := struct {
+		Path string          `json:"path"`
+		Op   string          `json:"op"`
+		Old  json.RawMessage `json:"old,omitempty"`
+		New  json.RawMessage `json:"new,omitempty"`
+	}{Path: c.Path, Op: c.Op}
+	if c.Op != ChangeAdded {
+		out.Old = ExtJSONValue(c.Old)
+	}
+	if c.Op != ChangeRemoved {
+		out.New = ExtJSONValue(c.New)
+	}
+	return json.Marshal(out)
+}
+
+// DiffDocuments 对比两个文档，子文档逐字段递归对比，数组按元素下标对比
+func DiffDocuments(before, after bson.D) []FieldChange {
+	var changes []FieldChange
+	diffValue("", before, after, &changes)
+	return changes
+}
+
+func diffValue(path string, before, after interface{}, changes *[]FieldChange) {
+	beforeDoc, beforeIsDoc := asDocument(before)
+	afterDoc, afterIsDoc := asDocument(after)
+	if beforeIsDoc && afterIsDoc {
+		diffDocument(path, beforeDoc, afterDoc, changes)
+		return
+	}
+
+	beforeArr, beforeIsArr := before.(bson.A)
+	afterArr, afterIsArr := after.(bson.A)
+	if beforeIsArr && afterIsArr {
+		for i := 0; i < len(beforeArr) || i < len(afterArr); i++ {
+			elemPath := joinPath(path, strconv.Itoa(i))
+			switch {
+			case i >= len(afterArr):
+				*changes = append(*changes, FieldChange{Path: elemPath, Op: ChangeRemoved, Old: beforeArr[i]})
+			case i >= len(beforeArr):
+				*changes = append(*changes, FieldChange{Path: elemPath, Op: ChangeAdded, New: afterArr[i]})
+			default:
+				diffValue(elemPath, beforeArr[i], afterArr[i], changes)
+			}
+		}
+		return
+	}
+
+	if !ValuesEqual(before, after) {
+		*changes = append(*changes, FieldChange{Path: path, Op: ChangeChanged, Old: before, New: after})
+	}
+}
+
+func diffDocument(path string, before, after bson.D, changes *[]FieldChange) {
+	afterKeys := make(map[string]interface{}, len(after))
+	for _, e := range after {
+		afterKeys[e.Key] = e.Value
+	}
+	beforeKeys := make(map[string]bool, len(before))
+
+	for _, e := range before {
+		beforeKeys[e.Key] = true
+		fieldPath := joinPath(path, e.Key)
+		if value, ok := afterKeys[e.Key]; ok {
+			diffValue(fieldPath, e.Value, value, changes)
+		} else {
+			*changes = append(*changes, FieldChange{Path: fieldPath, Op: ChangeRemoved, Old: e.Value})
+		}
+	}
+	for _, e := range after {
+		if !beforeKeys[e.Key] {
+			*changes = append(*changes, FieldChange{Path: joinPath(path, e.Key), Op: ChangeAdded, New: e.Value})
+		}
+	}
+}
+
+func joinPath(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// asDocument 将bson.D、bson.M统一为bson.D，bson.M按键名排序
+func asDocument(v interface{}) (bson.D, bool) {
+	switch doc := v.(type) {
+	case bson.D:
+		return doc, true
+	case bson.M:
+		return mapToD(doc), true
+	case map[string]interface{}:
+		return mapToD(doc), true
+	}
+	return nil, false
+}
+
+func mapToD(m map[string]interface{}) bson.D {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	doc := make(bson.D, 0, len(keys))
+	for _, k := range keys {
+		doc = append(doc, bson.E{Key: k, Value: m[k]})
+	}
+	return doc
+}
+
+// ValuesEqual 按BSON编码比较两个值，数值类型按数值大小比较
+func ValuesEqual(a, b interface{}) bool {
+	if fa, ok := toFloat(a); ok {
+		if fb, ok := toFloat(b); ok {
+			return fa == fb
+		}
+	}
+	ta, da, errA := bson.MarshalValue(a)
+	tb, db, errB := bson.MarshalValue(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	return ta == tb && bytes.Equal(da, db)
+}
+
+// toFloat 将数值类型转换为float64
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case primitive.Decimal128:
+		f, err := strconv.ParseFloat(n.String(), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,48 @@
+package database
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrDocumentModified 文档在读取之后已被其他人修改或删除
+var ErrDocumentModified = errors.New("document has been modified by someone else")
+
+// DocumentETag 以文档BSON编码的SHA-256作为强ETag，字段顺序和类型的变化都会改变ETag
+func DocumentETag(doc bson.D) (string, error) {
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`, nil
+}
+
+// documentFilter 按_id定位文档；original不为空时要求文档内容与original完全一致，
+// 保证写入基于的是客户端看到的版本
+func documentFilter(id primitive.ObjectID, original bson.D) bson.D {
+	filter := bson.D{{Key: "_id", Value: id}}
+	if original != nil {
+		filter = append(filter, bson.E{Key: "$expr", Value: bson.D{{Key: "$eq", Value: bson.A{
+			"$$ROOT",
+			bson.D{{Key: "$literal", Value: original}},
+		}}}})
+	}
+	return filter
+}
+
+// matchError 写操作未匹配到文档时，按是否指定了original区分为未找到或已被修改
+func matchError(matched int64, original bson.D) error {
+	if matched > 0 {
+		return nil
+	}
+	if original != nil {
+		return ErrDocumentModified
+	}
+	return mongo.ErrNoDocuments
+}
@@ -0,0 +1,180 @@
+package database
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// earthRadiusMeters 地球半径（米），用于把以米为单位的距离换算为 $centerSphere 的弧度
+const earthRadiusMeters = 6378100
+
+// TextIndex 集合的全文索引，Fields为参与全文搜索的字段（"$**"表示所有字符串字段）及其权重
+type TextIndex struct {
+	Name            string           `json:"name"`
+	Fields          map[string]int32 `json:"fields"`
+	DefaultLanguage string           `json:"defaultLanguage,omitempty"`
+}
+
+// GeoIndex 地理空间索引，Type为 2dsphere 或 2d
+type GeoIndex struct {
+	Name  string `json:"name"`
+	Field string `json:"field"`
+	Type  string `json:"type"`
+}
+
+// QueryIndexes $text 和地理空间查询可以使用的索引
+type QueryIndexes struct {
+	Text *TextIndex `json:"text"`
+	Geo  []GeoIndex `json:"geo"`
+}
+
+// GetQueryIndexes 列出集合的全文索引和地理空间索引，一个集合最多只有一个全文索引
+func (s *Service) GetQueryIndexes(ctx context.Context, dbName, collectionName string) (*QueryIndexes, error) {
+	ctx, cancel := s.withTimeout(ctx, s.timeouts.Read)
+	defer cancel()
+
+	cursor, err := s.client.Database(dbName).Collection(collectionName).Indexes().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var specs []struct {
+		Name            string           `bson:"name"`
+		Key             bson.D           `bson:"key"`
+		Weights         map[string]int32 `bson:"weights"`
+		DefaultLanguage string           `bson:"default_language"`
+	}
+	if err := cursor.All(ctx, &specs); err != nil {
+		return nil, err
+	}
+
+	indexes := &QueryIndexes{Geo: []GeoIndex{}}
+	for _, spec := range specs {
+		for _, key := range spec.Key {
+			switch key.Value {
+			case "text":
+				if indexes.Text == nil {
+					indexes.Text = &TextIndex{Name: spec.Name, Fields: spec.Weights, DefaultLanguage: spec.DefaultLanguage}
+				}
+			case "2dsphere", "2d":
+				indexes.Geo = append(indexes.Geo, GeoIndex{Name: spec.Name, Field: key.Key, Type: key.Value.(string)})
+			}
+		}
+	}
+	return indexes, nil
+}
+
+// usesNear 查询条件的顶层或 $and 中是否有 $near/$nearSphere。这类查询的结果按距离排序，
+// 并且不能用于 countDocuments
+func usesNear(filter bson.D) bool {
+	for _, elem := range filter {
+		switch value := elem.Value.(type) {
+		case bson.D:
+			for _, op := range value {
+				if op.Key == "$near" || op.Key == "$nearSphere" {
+					return true
+				}
+			}
+		case bson.A:
+			if elem.Key != "$and" {
+				continue
+			}
+			for _, item := range value {
+				if clause, ok := item.(bson.D); ok && usesNear(clause) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// countFilter 按MongoDB文档的建议把 $near 改写为 $geoWithin $center、$nearSphere 改写为 $centerSphere，
+// 以便计算总数；没有 $maxDistance 时只要求字段存在，$minDistance 不参与计数
+func countFilter(filter bson.D) bson.D {
+	if !usesNear(filter) {
+		return filter
+	}
+	rewritten := make(bson.D, 0, len(filter))
+	for _, elem := range filter {
+		switch value := elem.Value.(type) {
+		case bson.D:
+			elem.Value = countCondition(value)
+		case bson.A:
+			if elem.Key == "$and" {
+				clauses := make(bson.A, len(value))
+				for i, item := range value {
+					if clause, ok := item.(bson.D); ok {
+						item = countFilter(clause)
+					}
+					clauses[i] = item
+				}
+				elem.Value = clauses
+			}
+		}
+		rewritten = append(rewritten, elem)
+	}
+	return rewritten
+}
+
+// countCondition 改写字段条件中的 $near/$nearSphere，其他运算符保持不变
+func countCondition(condition bson.D) bson.D {
+	var op string
+	var near, maxDistance interface{}
+	for _, e := range condition {
+		switch e.Key {
+		case "$near", "$nearSphere":
+			op, near = e.Key, e.Value
+		case "$maxDistance":
+			maxDistance = e.Value
+		}
+	}
+	if op == "" {
+		return condition
+	}
+
+	// 旧式坐标对的$near为平面距离，$nearSphere为弧度；GeoJSON点的距离单位为米，$near和$nearSphere都按球面计算，
+	// 此时$maxDistance也可以写在$near的文档中
+	center, spherical := near, op == "$nearSphere"
+	if point, ok := near.(bson.D); ok {
+		var geometry interface{}
+		for _, e := range point {
+			switch e.Key {
+			case "$geometry":
+				geometry = e.Value
+			case "$maxDistance":
+				maxDistance = e.Value
+			}
+		}
+		if geometry, ok := geometry.(bson.D); ok {
+			center, spherical = nil, true
+			for _, g := range geometry {
+				if g.Key == "coordinates" {
+					center = g.Value
+				}
+			}
+			if distance, ok := toFloat(maxDistance); ok {
+				maxDistance = distance / earthRadiusMeters
+			}
+		}
+	}
+
+	rewritten := bson.D{}
+	for _, e := range condition {
+		switch e.Key {
+		case "$near", "$nearSphere", "$maxDistance", "$minDistance":
+			continue
+		}
+		rewritten = append(rewritten, e)
+	}
+	if maxDistance == nil {
+		return append(rewritten, bson.E{Key: "$exists", Value: true})
+	}
+	shape := "$center"
+	if spherical {
+		shape = "$centerSphere"
+	}
+	return append(rewritten, bson.E{Key: "$geoWithin", Value: bson.D{{Key: shape, Value: bson.A{center, maxDistance}}}})
+}
@@ -1,5 +1,7 @@
 package database
 
+import "go.mongodb.org/mongo-driver/bson"
+
 // DatabaseInfo 数据库信息
 type DatabaseInfo struct {
 	Name       string        `json:"name"`
@@ -17,6 +19,14 @@ type DatabaseStats struct {
 	StorageSize int64 `json:"storageSize,omitempty"`
 }
 
+// Query 文档查询条件；Pipeline不为空时按聚合管道查询，忽略其他条件
+type Query struct {
+	Filter     bson.D
+	Sort       bson.D
+	Projection bson.D
+	Pipeline   []bson.D
+}
+
 // DocumentsResponse 文档响应
 type DocumentsResponse struct {
 	Documents []map[string]interface{} `json:"documents"`
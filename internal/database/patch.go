@@ -0,0 +1,285 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ErrImmutableID 替换或修补后的文档改变了_id
+var ErrImmutableID = errors.New("_id is immutable and cannot be changed")
+
+// ErrPatchTestFailed JSON Patch中的test操作不成立
+var ErrPatchTestFailed = errors.New("json patch test operation failed")
+
+// JSONPatchOperation RFC 6902 JSON Patch的单个操作
+type JSONPatchOperation struct {
+	Op    string        `bson:"op"`
+	Path  string        `bson:"path"`
+	From  string        `bson:"from,omitempty"`
+	Value bson.RawValue `bson:"value,omitempty"`
+}
+
+// MergePatch 按RFC 7386 JSON Merge Patch合并文档：null删除字段，子文档递归合并，其他值直接替换
+func MergePatch(target, patch bson.D) bson.D {
+	result := copyValue(target).(bson.D)
+	for _, e := range patch {
+		index := -1
+		for i, existing := range result {
+			if existing.Key == e.Key {
+				index = i
+				break
+			}
+		}
+
+		if e.Value == nil {
+			if index >= 0 {
+				result = append(result[:index], result[index+1:]...)
+			}
+			continue
+		}
+
+		value := e.Value
+		if patchDoc, ok := asDocument(e.Value); ok {
+			var current bson.D
+			if index >= 0 {
+				current, _ = asDocument(result[index].Value)
+			}
+			if current == nil {
+				current = bson.D{}
+			}
+			value = MergePatch(current, patchDoc)
+		}
+
+		if index >= 0 {
+			result[index].Value = value
+		} else {
+			result = append(result, bson.E{Key: e.Key, Value: value})
+		}
+	}
+	return result
+}
+
+// ApplyJSONPatch 按RFC 6902依次执行JSON Patch操作，任一操作失败时整体失败且不修改原文档
+func ApplyJSONPatch(doc bson.D, operations []JSONPatchOperation) (bson.D, error) {
+	var result interface{} = copyValue(doc)
+	for i, operation := range operations {
+		var err error
+		if result, err = applyPatchOperation(result, operation); err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %w", i, operation.Op, operation.Path, err)
+		}
+	}
+	return result.(bson.D), nil
+}
+
+func applyPatchOperation(doc interface{}, operation JSONPatchOperation) (interface{}, error) {
+	tokens, err := parsePointer(operation.Path)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("operations on the document root are not supported")
+	}
+
+	switch operation.Op {
+	case "add", "replace", "test":
+		if operation.Value.Type == 0 {
+			return nil, fmt.Errorf("value is required")
+		}
+		var value interface{}
+		if err := operation.Value.Unmarshal(&value); err != nil {
+			return nil, err
+		}
+		switch operation.Op {
+		case "add":
+			return pointerUpdate(doc, tokens, func(container interface{}, key string) (interface{}, error) {
+				return pointerAdd(container, key, value)
+			})
+		case "replace":
+			return pointerUpdate(doc, tokens, func(container interface{}, key string) (interface{}, error) {
+				return pointerReplace(container, key, value)
+			})
+		default:
+			current, err := pointerGet(doc, tokens)
+			if err != nil {
+				return nil, err
+			}
+			if !ValuesEqual(current, value) {
+				return nil, ErrPatchTestFailed
+			}
+			return doc, nil
+		}
+
+	case "remove":
+		return pointerUpdate(doc, tokens, pointerRemove)
+
+	case "move", "copy":
+		fromTokens, err := parsePointer(operation.From)
+		if err != nil {
+			return nil, err
+		}
+		if len(fromTokens) == 0 {
+			return nil, fmt.Errorf("from must not be the document root")
+		}
+		value, err := pointerGet(doc, fromTokens)
+		if err != nil {
+			return nil, err
+		}
+		if operation.Op == "move" {
+			if strings.HasPrefix(operation.Path+"/", operation.From+"/") {
+				return nil, fmt.Errorf("cannot move a value into one of its children")
+			}
+			if doc, err = pointerUpdate(doc, fromTokens, pointerRemove); err != nil {
+				return nil, err
+			}
+		} else {
+			value = copyValue(value)
+		}
+		return pointerUpdate(doc, tokens, func(container interface{}, key string) (interface{}, error) {
+			return pointerAdd(container, key, value)
+		})
+	}
+	return nil, fmt.Errorf("unknown operation %q", operation.Op)
+}
+
+// parsePointer 解析RFC 6901 JSON Pointer，空字符串表示文档根
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// pointerGet 获取指针指向的值
+func pointerGet(node interface{}, tokens []string) (interface{}, error) {
+	for _, token := range tokens {
+		var err error
+		if node, err = pointerChild(node, token); err != nil {
+			return nil, err
+		}
+	}
+	return node, nil
+}
+
+// pointerUpdate 定位到指针的父容器并调用fn修改，返回修改后的节点；数组长度可能变化，因此逐层写回
+func pointerUpdate(node interface{}, tokens []string, fn func(container interface{}, key string) (interface{}, error)) (interface{}, error) {
+	if len(tokens) == 1 {
+		return fn(node, tokens[0])
+	}
+	child, err := pointerChild(node, tokens[0])
+	if err != nil {
+		return nil, err
+	}
+	updated, err := pointerUpdate(child, tokens[1:], fn)
+	if err != nil {
+		return nil, err
+	}
+	return pointerReplace(node, tokens[0], updated)
+}
+
+func pointerChild(node interface{}, key string) (interface{}, error) {
+	if doc, ok := asDocument(node); ok {
+		for _, e := range doc {
+			if e.Key == key {
+				return e.Value, nil
+			}
+		}
+		return nil, fmt.Errorf("path segment %q not found", key)
+	}
+	if arr, ok := node.(bson.A); ok {
+		index, err := arrayIndex(key, len(arr)-1)
+		if err != nil {
+			return nil, err
+		}
+		return arr[index], nil
+	}
+	return nil, fmt.Errorf("path segment %q does not refer to a document or array", key)
+}
+
+func pointerAdd(container interface{}, key string, value interface{}) (interface{}, error) {
+	if doc, ok := asDocument(container); ok {
+		for i, e := range doc {
+			if e.Key == key {
+				doc[i].Value = value
+				return doc, nil
+			}
+		}
+		return append(doc, bson.E{Key: key, Value: value}), nil
+	}
+	if arr, ok := container.(bson.A); ok {
+		if key == "-" {
+			return append(arr, value), nil
+		}
+		index, err := arrayIndex(key, len(arr))
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, nil)
+		copy(arr[index+1:], arr[index:])
+		arr[index] = value
+		return arr, nil
+	}
+	return nil, fmt.Errorf("cannot add %q to a non-container value", key)
+}
+
+func pointerReplace(container interface{}, key string, value interface{}) (interface{}, error) {
+	if doc, ok := asDocument(container); ok {
+		for i, e := range doc {
+			if e.Key == key {
+				doc[i].Value = value
+				return doc, nil
+			}
+		}
+		return nil, fmt.Errorf("path segment %q not found", key)
+	}
+	if arr, ok := container.(bson.A); ok {
+		index, err := arrayIndex(key, len(arr)-1)
+		if err != nil {
+			return nil, err
+		}
+		arr[index] = value
+		return arr, nil
+	}
+	return nil, fmt.Errorf("path segment %q does not refer to a document or array", key)
+}
+
+func pointerRemove(container interface{}, key string) (interface{}, error) {
+	if doc, ok := asDocument(container); ok {
+		for i, e := range doc {
+			if e.Key == key {
+				return append(doc[:i], doc[i+1:]...), nil
+			}
+		}
+		return nil, fmt.Errorf("path segment %q not found", key)
+	}
+	if arr, ok := container.(bson.A); ok {
+		index, err := arrayIndex(key, len(arr)-1)
+		if err != nil {
+			return nil, err
+		}
+		return append(arr[:index], arr[index+1:]...), nil
+	}
+	return nil, fmt.Errorf("path segment %q does not refer to a document or array", key)
+}
+
+// arrayIndex 解析数组下标，必须是不含前导零的非负整数且不超过max
+func arrayIndex(token string, max int) (int, error) {
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	if index > max {
+		return 0, fmt.Errorf("array index %d out of range", index)
+	}
+	return index, nil
+}
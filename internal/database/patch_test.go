@@ -0,0 +1,241 @@
+package database
+
+import (
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// testDoc 解析relaxed Extended JSON文档
+func testDoc(t *testing.T, s string) bson.D {
+	t.Helper()
+	var doc bson.D
+	if err := bson.UnmarshalExtJSON([]byte(s), false, &doc); err != nil {
+		t.Fatalf("invalid document %s: %v", s, err)
+	}
+	return doc
+}
+
+// testOperations 按bindJSONPatch的方式解析JSON Patch操作数组
+func testOperations(t *testing.T, s string) []JSONPatchOperation {
+	t.Helper()
+	var wrapper struct {
+		Operations []JSONPatchOperation `bson:"operations"`
+	}
+	if err := bson.UnmarshalExtJSON([]byte(`{"operations":`+s+`}`), false, &wrapper); err != nil {
+		t.Fatalf("invalid operations %s: %v", s, err)
+	}
+	return wrapper.Operations
+}
+
+func testJSON(t *testing.T, doc bson.D) string {
+	t.Helper()
+	data, err := bson.MarshalExtJSON(doc, false, false)
+	if err != nil {
+		t.Fatalf("marshal %v: %v", doc, err)
+	}
+	return string(data)
+}
+
+// RFC 7386 附录A中补丁为对象的用例
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		target, patch, want string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+		// RFC 7386 第3节的示例
+		{
+			`{"title":"Goodbye!","author":{"givenName":"John","familyName":"Doe"},"tags":["example","sample"],"content":"This will be unchanged"}`,
+			`{"title":"Hello!","phoneNumber":"+01-123-456-7890","author":{"familyName":null},"tags":["example"]}`,
+			`{"title":"Hello!","author":{"givenName":"John"},"tags":["example"],"content":"This will be unchanged","phoneNumber":"+01-123-456-7890"}`,
+		},
+		// 点号只是字段名的一部分
+		{`{"a.b":1,"a":{"b":2}}`, `{"a.b":null,"a":{"b":3}}`, `{"a":{"b":3}}`},
+	}
+
+	for _, tt := range tests {
+		target := testDoc(t, tt.target)
+		before := testJSON(t, target)
+		got := testJSON(t, MergePatch(target, testDoc(t, tt.patch)))
+		if want := testJSON(t, testDoc(t, tt.want)); got != want {
+			t.Errorf("MergePatch(%s, %s)\n got: %s\nwant: %s", tt.target, tt.patch, got, want)
+		}
+		if after := testJSON(t, target); after != before {
+			t.Errorf("MergePatch modified the target: %s", after)
+		}
+	}
+}
+
+// RFC 6902 附录A的用例，另加点号字段名和数组下标的边界情况
+func TestApplyJSONPatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		doc        string
+		operations string
+		want       string
+		err        error // 为nil且want为空时只要求返回错误
+	}{
+		{
+			name:       "A.1 add object member",
+			doc:        `{"foo":"bar"}`,
+			operations: `[{"op":"add","path":"/baz","value":"qux"}]`,
+			want:       `{"foo":"bar","baz":"qux"}`,
+		},
+		{
+			name:       "A.2 add array element",
+			doc:        `{"foo":["bar","baz"]}`,
+			operations: `[{"op":"add","path":"/foo/1","value":"qux"}]`,
+			want:       `{"foo":["bar","qux","baz"]}`,
+		},
+		{
+			name:       "A.3 remove object member",
+			doc:        `{"baz":"qux","foo":"bar"}`,
+			operations: `[{"op":"remove","path":"/baz"}]`,
+			want:       `{"foo":"bar"}`,
+		},
+		{
+			name:       "A.4 remove array element",
+			doc:        `{"foo":["bar","qux","baz"]}`,
+			operations: `[{"op":"remove","path":"/foo/1"}]`,
+			want:       `{"foo":["bar","baz"]}`,
+		},
+		{
+			name:       "A.5 replace",
+			doc:        `{"baz":"qux","foo":"bar"}`,
+			operations: `[{"op":"replace","path":"/baz","value":"boo"}]`,
+			want:       `{"baz":"boo","foo":"bar"}`,
+		},
+		{
+			name:       "A.6 move value",
+			doc:        `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+			operations: `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			want:       `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`,
+		},
+		{
+			name:       "A.7 move array element",
+			doc:        `{"foo":["all","grass","cows","eat"]}`,
+			operations: `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`,
+			want:       `{"foo":["all","cows","eat","grass"]}`,
+		},
+		{
+			name:       "A.8 test success",
+			doc:        `{"baz":"qux","foo":["a",2,"c"]}`,
+			operations: `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`,
+			want:       `{"baz":"qux","foo":["a",2,"c"]}`,
+		},
+		{
+			name:       "A.9 test failure",
+			doc:        `{"baz":"qux"}`,
+			operations: `[{"op":"test","path":"/baz","value":"bar"}]`,
+			err:        ErrPatchTestFailed,
+		},
+		{
+			name:       "A.10 add nested member object",
+			doc:        `{"foo":"bar"}`,
+			operations: `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`,
+			want:       `{"foo":"bar","child":{"grandchild":{}}}`,
+		},
+		{
+			name:       "A.12 add to nonexistent target",
+			doc:        `{"foo":"bar"}`,
+			operations: `[{"op":"add","path":"/baz/bat","value":"qux"}]`,
+		},
+		{
+			name:       "A.14 escape ordering",
+			doc:        `{"/":9,"~1":10}`,
+			operations: `[{"op":"test","path":"/~01","value":10}]`,
+			want:       `{"/":9,"~1":10}`,
+		},
+		{
+			name:       "A.15 compare string and number",
+			doc:        `{"/":9,"~1":10}`,
+			operations: `[{"op":"test","path":"/~01","value":"10"}]`,
+			err:        ErrPatchTestFailed,
+		},
+		{
+			name:       "A.16 add array value with -",
+			doc:        `{"foo":["bar"]}`,
+			operations: `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`,
+			want:       `{"foo":["bar",["abc","def"]]}`,
+		},
+		{
+			name:       "copy",
+			doc:        `{"a":{"b":[1]}}`,
+			operations: `[{"op":"copy","from":"/a","path":"/c"},{"op":"add","path":"/c/b/-","value":2}]`,
+			want:       `{"a":{"b":[1]},"c":{"b":[1,2]}}`,
+		},
+		{
+			name:       "dotted field name",
+			doc:        `{"a.b":1,"a":{"b":2}}`,
+			operations: `[{"op":"replace","path":"/a.b","value":3}]`,
+			want:       `{"a.b":3,"a":{"b":2}}`,
+		},
+		{
+			name:       "escaped slash and tilde",
+			doc:        `{"a/b":{"c~d":1}}`,
+			operations: `[{"op":"replace","path":"/a~1b/c~0d","value":2}]`,
+			want:       `{"a/b":{"c~d":2}}`,
+		},
+		{
+			name:       "array index out of range",
+			doc:        `{"foo":["bar"]}`,
+			operations: `[{"op":"add","path":"/foo/2","value":"x"}]`,
+		},
+		{
+			name:       "array index with leading zero",
+			doc:        `{"foo":["bar","baz"]}`,
+			operations: `[{"op":"remove","path":"/foo/01"}]`,
+		},
+		{
+			name:       "remove missing member",
+			doc:        `{"foo":"bar"}`,
+			operations: `[{"op":"remove","path":"/baz"}]`,
+		},
+		{
+			name:       "move into own child",
+			doc:        `{"a":{"b":1}}`,
+			operations: `[{"op":"move","from":"/a","path":"/a/c"}]`,
+		},
+		{
+			name:       "failure leaves document unchanged",
+			doc:        `{"foo":"bar"}`,
+			operations: `[{"op":"add","path":"/baz","value":1},{"op":"test","path":"/foo","value":"x"}]`,
+			err:        ErrPatchTestFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := testDoc(t, tt.doc)
+			got, err := ApplyJSONPatch(doc, testOperations(t, tt.operations))
+			if after := testJSON(t, doc); after != testJSON(t, testDoc(t, tt.doc)) {
+				t.Errorf("ApplyJSONPatch modified the original document: %s", after)
+			}
+			if tt.want == "" {
+				if err == nil {
+					t.Fatalf("expected an error, got %s", testJSON(t, got))
+				}
+				if tt.err != nil && !errors.Is(err, tt.err) {
+					t.Errorf("error = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got, want := testJSON(t, got), testJSON(t, testDoc(t, tt.want)); got != want {
+				t.Errorf("got: %s\nwant: %s", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,499 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// 引用的识别方式
+const (
+	// ReferenceDBRef 子文档为 {$ref, $id, $db} 形式的DBRef
+	ReferenceDBRef = "dbref"
+	// ReferenceMapping 字段配置了引用映射
+	ReferenceMapping = "mapping"
+	// ReferenceHeuristic 按字段名推断集合，或在各集合中查找_id相同的文档
+	ReferenceHeuristic = "heuristic"
+)
+
+const (
+	// maxProbeCollections 推断引用时最多查找的集合数
+	maxProbeCollections = 50
+	// maxProbeIDs 推断引用时每个字段最多用于查找的值的个数
+	maxProbeIDs = 100
+	// referrerSampleSize 查找引用方时每个集合采样的文档数，用于确定可能包含引用的字段
+	referrerSampleSize = 200
+	// referrerMaxCollections 查找引用方时最多采样的集合数
+	referrerMaxCollections = 20
+	// referrerMaxFields 查找引用方时最多查询的字段数（不含引用映射），这些字段通常没有索引
+	referrerMaxFields = 30
+)
+
+// 查找引用方未完成的原因
+const (
+	ReferrersPartialCollections = "collections"
+	ReferrersPartialFields      = "fields"
+	ReferrersPartialTime        = "time"
+)
+
+// ReferenceTarget 被引用的集合
+type ReferenceTarget struct {
+	Database   string
+	Collection string
+}
+
+// ReferenceField 可能包含引用的字段
+type ReferenceField struct {
+	Database   string
+	Collection string
+	Field      string
+}
+
+// Reference 文档中的一个引用，Path为被引用_id值所在的路径（数组下标以数字表示，DBRef为 field.$id）
+type Reference struct {
+	Document   int             `json:"document"`
+	Path       string          `json:"path"`
+	ID         json.RawMessage `json:"id"`
+	Database   string          `json:"database"`
+	Collection string          `json:"collection"`
+	Source     string          `json:"source"`
+	Exists     bool            `json:"exists"`
+}
+
+// Referrer 引用了某文档的一组文档：Database.Collection 中 Path 字段的值为该文档的_id
+type Referrer struct {
+	Database   string            `json:"database"`
+	Collection string            `json:"collection"`
+	Path       string            `json:"path"`
+	Source     string            `json:"source"`
+	IDs        []json.RawMessage `json:"ids"`
+	More       bool              `json:"more"`
+}
+
+// ReferrersResult 查找引用方的结果，Partial不为空表示因集合数、字段数或时间上限没有查找完
+type ReferrersResult struct {
+	Referrers []Referrer `json:"referrers"`
+	Partial   string     `json:"partial,omitempty"`
+}
+
+// referenceCandidate 遍历文档时找到的可能的引用
+type referenceCandidate struct {
+	document int
+	path     string
+	field    string
+	id       interface{}
+	target   *ReferenceTarget
+	source   string
+}
+
+// ResolveReferences 识别文档中引用其他文档的值：DBRef、配置了映射的字段（mappings的键为不含数组下标的字段路径），
+// 以及其余ObjectId字段按字段名（如 userId、order_ids）推断或在数据库各集合中查找_id相同的文档。
+// 推断不出集合的值不返回；Exists表示被引用的文档是否存在
+func (s *Service) ResolveReferences(ctx context.Context, dbName string, documents []bson.D, mappings map[string]ReferenceTarget) ([]Reference, error) {
+	ctx, cancel := s.withTimeout(ctx, s.timeouts.Read)
+	defer cancel()
+
+	var candidates []*referenceCandidate
+	for i, doc := range documents {
+		candidates = append(candidates, referenceCandidates(doc, i, dbName, mappings)...)
+	}
+	if err := s.inferReferenceTargets(ctx, dbName, nil, candidates, nil); err != nil {
+		return nil, err
+	}
+
+	// 按被引用的集合分组检查文档是否存在
+	groups := make(map[ReferenceTarget][]interface{})
+	for _, candidate := range candidates {
+		if candidate.target != nil {
+			groups[*candidate.target] = append(groups[*candidate.target], candidate.id)
+		}
+	}
+	existing := make(map[ReferenceTarget]map[string]bool)
+	for target, ids := range groups {
+		found, err := s.existingIDs(ctx, target, ids)
+		if err != nil {
+			return nil, err
+		}
+		existing[target] = found
+	}
+
+	references := []Reference{}
+	for _, candidate := range candidates {
+		if candidate.target == nil {
+			continue
+		}
+		references = append(references, Reference{
+			Document:   candidate.document,
+			Path:       candidate.path,
+			ID:         CanonicalExtJSONValue(candidate.id),
+			Database:   candidate.target.Database,
+			Collection: candidate.target.Collection,
+			Source:     candidate.source,
+			Exists:     existing[*candidate.target][idKey(candidate.id)],
+		})
+	}
+	return references, nil
+}
+
+// referenceCandidates 文档中可能的引用：DBRef和配置了映射的字段直接确定集合，其余ObjectId值（_id除外）待推断
+func referenceCandidates(doc bson.D, index int, dbName string, mappings map[string]ReferenceTarget) []*referenceCandidate {
+	var candidates []*referenceCandidate
+	walkReferences(doc, "", "", func(path, field string, value interface{}, dbRef *ReferenceTarget) {
+		candidate := &referenceCandidate{document: index, path: path, field: field, id: value}
+		switch {
+		case dbRef != nil:
+			if dbRef.Database == "" {
+				dbRef.Database = dbName
+			}
+			candidate.target, candidate.source = dbRef, ReferenceDBRef
+		case mappings[field] != (ReferenceTarget{}):
+			target := mappings[field]
+			candidate.target, candidate.source = &target, ReferenceMapping
+		default:
+			if _, ok := value.(primitive.ObjectID); !ok || path == "_id" {
+				return
+			}
+			candidate.source = ReferenceHeuristic
+		}
+		candidates = append(candidates, candidate)
+	})
+	return candidates
+}
+
+// walkReferences 遍历文档中的值，path含数组下标，field不含；DBRef子文档整体作为一个值，不再深入
+func walkReferences(value interface{}, path, field string, visit func(path, field string, value interface{}, dbRef *ReferenceTarget)) {
+	join := func(prefix, key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "." + key
+	}
+	switch v := value.(type) {
+	case bson.D:
+		if target, id, ok := dbRef(v); ok {
+			visit(join(path, "$id"), join(field, "$id"), id, target)
+			return
+		}
+		for _, e := range v {
+			walkReferences(e.Value, join(path, e.Key), join(field, e.Key), visit)
+		}
+	case bson.A:
+		for i, item := range v {
+			walkReferences(item, join(path, strconv.Itoa(i)), field, visit)
+		}
+	default:
+		if path != "" {
+			visit(path, field, value, nil)
+		}
+	}
+}
+
+// dbRef 判断子文档是否为DBRef：第一个字段为$ref（字符串），第二个为$id，可选的$db为字符串
+func dbRef(doc bson.D) (*ReferenceTarget, interface{}, bool) {
+	if len(doc) < 2 || doc[0].Key != "$ref" || doc[1].Key != "$id" {
+		return nil, nil, false
+	}
+	collection, ok := doc[0].Value.(string)
+	if !ok {
+		return nil, nil, false
+	}
+	target := &ReferenceTarget{Collection: collection}
+	if len(doc) > 2 && doc[2].Key == "$db" {
+		if target.Database, ok = doc[2].Value.(string); !ok {
+			return nil, nil, false
+		}
+	}
+	return target, doc[1].Value, true
+}
+
+// inferReferenceTargets 为按字段名推断的候选值确定集合：同一字段的值在候选集合中依次用$in查找，
+// 第一个找到文档的集合即为该字段引用的集合；collections为nil时查找数据库中的所有集合。
+// probed不为nil时记录每个字段上次找到的集合，之后先用当前候选值在该集合中查找，找不到时再依次探测其他集合
+func (s *Service) inferReferenceTargets(ctx context.Context, dbName string, collections []string, candidates []*referenceCandidate, probed map[string]*ReferenceTarget) error {
+	byField := make(map[string][]*referenceCandidate)
+	var fields []string
+	for _, candidate := range candidates {
+		if candidate.source != ReferenceHeuristic {
+			continue
+		}
+		if _, exists := byField[candidate.field]; !exists {
+			fields = append(fields, candidate.field)
+		}
+		byField[candidate.field] = append(byField[candidate.field], candidate)
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+
+	if collections == nil {
+		var err error
+		if collections, err = s.probeCollections(ctx, dbName); err != nil {
+			return err
+		}
+	}
+	for _, field := range fields {
+		var ids []interface{}
+		for _, candidate := range byField[field] {
+			if len(ids) < maxProbeIDs {
+				ids = append(ids, candidate.id)
+			}
+		}
+
+		// 其他集合中同名字段引用的集合最先查找，但仍需用当前的候选值确认
+		guesses := guessCollections(field, collections)
+		if last := probed[field]; last != nil {
+			reordered := []string{last.Collection}
+			for _, collection := range guesses {
+				if collection != last.Collection {
+					reordered = append(reordered, collection)
+				}
+			}
+			guesses = reordered
+		}
+		for _, collection := range guesses {
+			target := ReferenceTarget{Database: dbName, Collection: collection}
+			found, err := s.existingIDs(ctx, target, ids)
+			if err != nil {
+				return err
+			}
+			if len(found) > 0 {
+				for _, candidate := range byField[field] {
+					candidate.target = &target
+				}
+				break
+			}
+		}
+		if target := byField[field][0].target; probed != nil && target != nil {
+			probed[field] = target
+		}
+	}
+	return nil
+}
+
+// probeCollections 可能被引用的集合，不含系统集合和历史版本集合
+func (s *Service) probeCollections(ctx context.Context, dbName string) ([]string, error) {
+	names, err := s.client.Database(dbName).ListCollectionNames(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	var collections []string
+	for _, name := range names {
+		if !strings.HasPrefix(name, "system.") && !strings.HasSuffix(name, RevisionSuffix) {
+			collections = append(collections, name)
+		}
+	}
+	sort.Strings(collections)
+	return collections, nil
+}
+
+// guessCollections 按字段名排列候选集合：去掉 Id、Ids、_id 等后缀后与集合名（忽略大小写、下划线和复数形式）
+// 相同的集合在前，其余集合在后，最多 maxProbeCollections 个
+func guessCollections(field string, collections []string) []string {
+	name := field[strings.LastIndex(field, ".")+1:]
+	lower := strings.ToLower(name)
+	for _, suffix := range []string{"_ids", "ids", "_id", "id"} {
+		if strings.HasSuffix(lower, suffix) && len(lower) > len(suffix) {
+			lower = lower[:len(lower)-len(suffix)]
+			break
+		}
+	}
+	base := normalizeCollectionName(lower)
+	names := map[string]bool{base: true, base + "s": true, base + "es": true}
+	if strings.HasSuffix(base, "y") {
+		names[base[:len(base)-1]+"ies"] = true
+	}
+
+	var matched, others []string
+	for _, collection := range collections {
+		if names[normalizeCollectionName(collection)] {
+			matched = append(matched, collection)
+		} else {
+			others = append(others, collection)
+		}
+	}
+	result := append(matched, others...)
+	if len(result) > maxProbeCollections {
+		result = result[:maxProbeCollections]
+	}
+	return result
+}
+
+func normalizeCollectionName(name string) string {
+	return strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(name))
+}
+
+// existingIDs 查找集合中存在的_id，返回以 idKey 为键的集合
+func (s *Service) existingIDs(ctx context.Context, target ReferenceTarget, ids []interface{}) (map[string]bool, error) {
+	collection := s.client.Database(target.Database).Collection(target.Collection)
+	cursor, err := collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}}, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	found := make(map[string]bool)
+	for cursor.Next(ctx) {
+		found[idKey(cursor.Current.Lookup("_id"))] = true
+	}
+	return found, cursor.Err()
+}
+
+// idKey 比较_id用的键，值与bson.RawValue按canonical Extended JSON比较
+func idKey(value interface{}) string {
+	if raw, ok := value.(bson.RawValue); ok {
+		var v interface{}
+		if err := raw.Unmarshal(&v); err != nil {
+			return ""
+		}
+		value = v
+	}
+	return string(CanonicalExtJSONValue(value))
+}
+
+// FindReferrers 查找引用了某文档的文档：指向该集合的引用映射（mappings为源集合及字段），数据库中指向该集合的DBRef，
+// 以及各集合中ObjectId类型的字段（由采样确定）。每组最多返回limit个文档的_id。
+// 采样的集合数和查询的字段数有上限，超过上限或读操作超时时返回已找到的结果并设置Partial
+func (s *Service) FindReferrers(ctx context.Context, dbName, collectionName, id string, mappings []ReferenceField, limit int64) (*ReferrersResult, error) {
+	objectID, err := toObjectID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	scanCtx, cancel := s.withTimeout(ctx, s.timeouts.Read)
+	defer cancel()
+
+	result := &ReferrersResult{Referrers: []Referrer{}}
+	// timedOut 读操作超时（而不是请求被取消）时记录为部分结果
+	timedOut := func(err error) bool {
+		if ctx.Err() == nil && (mongo.IsTimeout(err) || errors.Is(err, context.DeadlineExceeded)) {
+			result.Partial = ReferrersPartialTime
+			return true
+		}
+		return false
+	}
+
+	searched := make(map[ReferenceField]bool)
+	search := func(field ReferenceField, source string, filter bson.D) error {
+		if searched[field] {
+			return nil
+		}
+		searched[field] = true
+		referrer, err := s.findReferrer(scanCtx, field, source, filter, limit)
+		if err != nil || referrer == nil {
+			return err
+		}
+		result.Referrers = append(result.Referrers, *referrer)
+		return nil
+	}
+
+	for _, field := range mappings {
+		if err := search(field, ReferenceMapping, bson.D{{Key: field.Field, Value: objectID}}); err != nil {
+			if timedOut(err) {
+				return result, nil
+			}
+			return nil, err
+		}
+	}
+
+	collections, err := s.probeCollections(scanCtx, dbName)
+	if err != nil {
+		if timedOut(err) {
+			return result, nil
+		}
+		return nil, err
+	}
+	if len(collections) > referrerMaxCollections {
+		collections = collections[:referrerMaxCollections]
+		result.Partial = ReferrersPartialCollections
+	}
+	fieldSearches := 0
+	for _, collection := range collections {
+		docs, err := s.sampleDocuments(scanCtx, dbName, collection, referrerSampleSize)
+		if err != nil {
+			if timedOut(err) {
+				return result, nil
+			}
+			return nil, err
+		}
+		fields := make(map[string]*FieldStats)
+		for _, doc := range docs {
+			walkDocument(doc, "", 0, fields, make(map[string]bool))
+		}
+		paths := make([]string, 0, len(fields))
+		for path := range fields {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+
+		for _, path := range paths {
+			stats := fields[path]
+			field := ReferenceField{Database: dbName, Collection: collection, Field: path}
+			prefix := strings.TrimSuffix(path, "$id")
+			var filter bson.D
+			source := ReferenceHeuristic
+			switch {
+			case strings.HasSuffix(path, ".$id") && fields[prefix+"$ref"] != nil:
+				filter = bson.D{{Key: prefix + "$ref", Value: collectionName}, {Key: path, Value: objectID}}
+				source = ReferenceDBRef
+			case path != "_id" && !strings.Contains(path, "$") && (stats.Types["objectId"] > 0 || stats.ArrayTypes["objectId"] > 0):
+				filter = bson.D{{Key: path, Value: objectID}}
+			}
+			if filter == nil || searched[field] {
+				continue
+			}
+			if fieldSearches == referrerMaxFields {
+				result.Partial = ReferrersPartialFields
+				return result, nil
+			}
+			fieldSearches++
+			if err := search(field, source, filter); err != nil {
+				if timedOut(err) {
+					return result, nil
+				}
+				return nil, err
+			}
+		}
+	}
+	return result, nil
+}
+
+// findReferrer 按条件查找引用方，没有匹配的文档时返回nil
+func (s *Service) findReferrer(ctx context.Context, field ReferenceField, source string, filter bson.D, limit int64) (*Referrer, error) {
+	collection := s.client.Database(field.Database).Collection(field.Collection)
+	opts := options.Find().SetProjection(bson.M{"_id": 1}).SetLimit(limit + 1)
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	referrer := &Referrer{Database: field.Database, Collection: field.Collection, Path: field.Field, Source: source, IDs: []json.RawMessage{}}
+	for cursor.Next(ctx) {
+		if int64(len(referrer.IDs)) == limit {
+			referrer.More = true
+			break
+		}
+		var doc struct {
+			ID interface{} `bson:"_id"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+		referrer.IDs = append(referrer.IDs, CanonicalExtJSONValue(doc.ID))
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+	if len(referrer.IDs) == 0 {
+		return nil, nil
+	}
+	return referrer, nil
+}
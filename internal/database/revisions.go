@@ -0,0 +1,162 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// RevisionSuffix 保存历史版本的旁路集合后缀，集合 users 的历史版本保存在 users.revisions 中
+const RevisionSuffix = ".revisions"
+
+// Revision 文档的一个历史版本，保存的是修改之前的完整内容
+type Revision struct {
+	ID         primitive.ObjectID `bson:"_id"`
+	DocumentID interface{}        `bson:"documentId"`
+	Revision   int64              `bson:"revision"`
+	Operation  string             `bson:"operation"`
+	Document   bson.Raw           `bson:"document,omitempty"`
+	SavedBy    string             `bson:"savedBy"`
+	SavedAt    time.Time          `bson:"savedAt"`
+}
+
+// MarshalJSON 文档内容以relaxed Extended JSON输出
+func (r Revision) MarshalJSON() ([]byte, error) {
+	out := struct {
+		Revision  int64           `json:"revision"`
+		Operation string          `json:"operation"`
+		Document  json.RawMessage `json:"document,omitempty"`
+		SavedBy   string          `json:"savedBy"`
+		SavedAt   int64           `json:"savedAt"`
+	}{
+		Revision:  r.Revision,
+		Operation: r.Operation,
+		SavedBy:   r.SavedBy,
+		SavedAt:   r.SavedAt.Unix(),
+	}
+	if r.Document != nil {
+		out.Document = ExtJSONValue(r.Document)
+	}
+	return json.Marshal(out)
+}
+
+func (s *Service) revisions(dbName, collectionName string) *mongo.Collection {
+	return s.client.Database(dbName).Collection(collectionName + RevisionSuffix)
+}
+
+// SaveRevision 保存文档修改前的版本，keep大于0时只保留该文档最近的keep个版本
+func (s *Service) SaveRevision(ctx context.Context, dbName, collectionName string, document bson.D, operation, savedBy string, keep int64) (*Revision, error) {
+	ctx, cancel := s.withTimeout(ctx, s.timeouts.Write)
+	defer cancel()
+
+	raw, err := bson.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+	documentID := bson.Raw(raw).Lookup("_id")
+
+	collection := s.revisions(dbName, collectionName)
+
+	// 版本号在该文档已有的最大版本号上递增
+	var latest struct {
+		Revision int64 `bson:"revision"`
+	}
+	err = collection.FindOne(ctx, bson.M{"documentId": documentID},
+		options.FindOne().SetSort(bson.D{{Key: "revision", Value: -1}}).SetProjection(bson.M{"revision": 1}),
+	).Decode(&latest)
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		// 文档的第一个版本，确保按文档查询版本的索引存在
+		_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys: bson.D{{Key: "documentId", Value: 1}, {Key: "revision", Value: -1}},
+		})
+		if err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	}
+
+	revision := &Revision{
+		ID:         primitive.NewObjectID(),
+		DocumentID: documentID,
+		Revision:   latest.Revision + 1,
+		Operation:  operation,
+		Document:   raw,
+		SavedBy:    savedBy,
+		SavedAt:    time.Now().UTC().Truncate(time.Millisecond),
+	}
+	if _, err := collection.InsertOne(ctx, revision); err != nil {
+		return nil, err
+	}
+
+	if keep > 0 && revision.Revision > keep {
+		_, err := collection.DeleteMany(ctx, bson.M{
+			"documentId": documentID,
+			"revision":   bson.M{"$lte": revision.Revision - keep},
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return revision, nil
+}
+
+// RemoveRevision 删除一个版本，用于写入失败时撤销刚保存的版本
+func (s *Service) RemoveRevision(ctx context.Context, dbName, collectionName string, revision *Revision) error {
+	ctx, cancel := s.withTimeout(ctx, s.timeouts.Write)
+	defer cancel()
+
+	_, err := s.revisions(dbName, collectionName).DeleteOne(ctx, bson.M{"_id": revision.ID})
+	return err
+}
+
+// ListRevisions 按版本号倒序列出文档的历史版本，不包含文档内容
+func (s *Service) ListRevisions(ctx context.Context, dbName, collectionName, id string) ([]Revision, error) {
+	ctx, cancel := s.withTimeout(ctx, s.timeouts.Read)
+	defer cancel()
+
+	objectID, err := toObjectID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "revision", Value: -1}}).
+		SetProjection(bson.M{"document": 0})
+	cursor, err := s.revisions(dbName, collectionName).Find(ctx, bson.M{"documentId": objectID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	revisions := []Revision{}
+	if err := cursor.All(ctx, &revisions); err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+// GetRevision 获取文档的指定版本，不存在时返回mongo.ErrNoDocuments
+func (s *Service) GetRevision(ctx context.Context, dbName, collectionName, id string, revision int64) (*Revision, error) {
+	ctx, cancel := s.withTimeout(ctx, s.timeouts.Read)
+	defer cancel()
+
+	objectID, err := toObjectID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var result Revision
+	err = s.revisions(dbName, collectionName).FindOne(ctx, bson.M{"documentId": objectID, "revision": revision}).Decode(&result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -0,0 +1,505 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	// DefaultSampleSize 默认采样文档数
+	DefaultSampleSize = 1000
+	// MaxSampleSize 最大采样文档数
+	MaxSampleSize = 10000
+
+	// maxTrackedValues 每个字段统计出现次数的不同值数量上限，超出后新值不再计入高频值
+	maxTrackedValues = 1000
+	// topValuesLimit 返回的高频值数量
+	topValuesLimit = 10
+)
+
+// SchemaAnalysis 集合结构分析结果
+type SchemaAnalysis struct {
+	Database   string        `json:"database"`
+	Collection string        `json:"collection"`
+	SampleSize int64         `json:"sampleSize"`
+	Sampled    int64         `json:"sampled"`
+	Fields     []*FieldStats `json:"fields"`
+}
+
+// FieldStats 字段统计信息，Path为点分隔的字段路径，数组中子文档的字段与MongoDB查询语法一致（如 items.price）
+type FieldStats struct {
+	Path        string           `json:"path"`
+	Depth       int              `json:"depth"`
+	Types       map[string]int64 `json:"types"`
+	ArrayTypes  map[string]int64 `json:"arrayTypes,omitempty"`
+	Count       int64            `json:"count"`
+	Presence    float64          `json:"presence"`
+	Min         interface{}      `json:"min,omitempty"`
+	Max         interface{}      `json:"max,omitempty"`
+	Cardinality int64            `json:"cardinality"`
+	Unique      bool             `json:"unique"`
+	TopValues   []ValueCount     `json:"topValues,omitempty"`
+
+	occurrences int64
+	distinct    map[uint64]struct{}
+	values      map[uint64]*ValueCount
+	ranges      map[string]*valueRange
+}
+
+// ValueCount 值及其出现次数
+type ValueCount struct {
+	Value interface{} `json:"value"`
+	Count int64       `json:"count"`
+}
+
+// DominantType 出现次数最多的类型
+func (f *FieldStats) DominantType() string {
+	best, bestCount := "", int64(-1)
+	for t, n := range f.Types {
+		if n > bestCount || (n == bestCount && t < best) {
+			best, bestCount = t, n
+		}
+	}
+	return best
+}
+
+// valueRange 某一类可比较值的最小/最大值
+type valueRange struct {
+	min, max       bson.RawValue
+	minKey, maxKey float64
+	minStr, maxStr string
+}
+
+// AnalyzeSchema 使用$sample随机采样文档，统计每个字段路径的类型、出现比例、取值范围、基数和高频值
+func (s *Service) AnalyzeSchema(ctx context.Context, dbName, collectionName string, sampleSize int64) (*SchemaAnalysis, error) {
+	ctx, cancel := s.withTimeout(ctx, s.timeouts.Read)
+	defer cancel()
+
+	docs, err := s.sampleDocuments(ctx, dbName, collectionName, sampleSize)
+	if err != nil {
+		return nil, err
+	}
+
+	analysis := &SchemaAnalysis{
+		Database:   dbName,
+		Collection: collectionName,
+		SampleSize: normalizeSampleSize(sampleSize),
+	}
+	fields := make(map[string]*FieldStats)
+	for _, doc := range docs {
+		analysis.Sampled++
+		seen := make(map[string]bool)
+		walkDocument(doc, "", 0, fields, seen)
+	}
+
+	for _, field := range fields {
+		field.finish(analysis.Sampled)
+		analysis.Fields = append(analysis.Fields, field)
+	}
+	sort.Slice(analysis.Fields, func(i, k int) bool { return analysis.Fields[i].Path < analysis.Fields[k].Path })
+	return analysis, nil
+}
+
+// normalizeSampleSize 限制采样数量范围
+func normalizeSampleSize(size int64) int64 {
+	if size <= 0 {
+		return DefaultSampleSize
+	}
+	if size > MaxSampleSize {
+		return MaxSampleSize
+	}
+	return size
+}
+
+// sampleDocuments 随机采样文档，返回原始BSON以保留类型信息
+func (s *Service) sampleDocuments(ctx context.Context, dbName, collectionName string, sampleSize int64) ([]bson.Raw, error) {
+	collection := s.client.Database(dbName).Collection(collectionName)
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$sample", Value: bson.M{"size": normalizeSampleSize(sampleSize)}}},
+	}
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var docs []bson.Raw
+	for cursor.Next(ctx) {
+		docs = append(docs, append(bson.Raw(nil), cursor.Current...))
+	}
+	return docs, cursor.Err()
+}
+
+// walkDocument 遍历文档中的所有字段，seen用于保证每个文档对同一路径只计数一次
+func walkDocument(doc bson.Raw, prefix string, depth int, fields map[string]*FieldStats, seen map[string]bool) {
+	elements, err := doc.Elements()
+	if err != nil {
+		return
+	}
+	for _, elem := range elements {
+		path := elem.Key()
+		if prefix != "" {
+			path = prefix + "." + path
+		}
+		field := fields[path]
+		if field == nil {
+			field = newFieldStats(path, depth)
+			fields[path] = field
+		}
+		if !seen[path] {
+			seen[path] = true
+			field.Count++
+		}
+		field.observe(elem.Value())
+
+		value := elem.Value()
+		switch value.Type {
+		case bsontype.EmbeddedDocument:
+			walkDocument(value.Document(), path, depth+1, fields, seen)
+		case bsontype.Array:
+			walkArray(value.Array(), field, path, depth, fields, seen)
+		}
+	}
+}
+
+// walkArray 统计数组元素类型，子文档元素的字段按 数组路径.字段 记录
+func walkArray(arr bson.Raw, field *FieldStats, path string, depth int, fields map[string]*FieldStats, seen map[string]bool) {
+	values, err := arr.Values()
+	if err != nil {
+		return
+	}
+	for _, value := range values {
+		field.ArrayTypes[TypeAlias(value.Type)]++
+		if value.Type == bsontype.EmbeddedDocument {
+			walkDocument(value.Document(), path, depth+1, fields, seen)
+		}
+	}
+}
+
+func newFieldStats(path string, depth int) *FieldStats {
+	return &FieldStats{
+		Path:       path,
+		Depth:      depth,
+		Types:      make(map[string]int64),
+		ArrayTypes: make(map[string]int64),
+		distinct:   make(map[uint64]struct{}),
+		values:     make(map[uint64]*ValueCount),
+		ranges:     make(map[string]*valueRange),
+	}
+}
+
+// observe 记录一次字段取值
+func (f *FieldStats) observe(value bson.RawValue) {
+	f.occurrences++
+	f.Types[TypeAlias(value.Type)]++
+
+	h := fnv.New64a()
+	h.Write([]byte{byte(value.Type)})
+	h.Write(value.Value)
+	key := h.Sum64()
+	f.distinct[key] = struct{}{}
+
+	// 只统计标量的高频值
+	if value.Type == bsontype.EmbeddedDocument || value.Type == bsontype.Array {
+		return
+	}
+	if vc, ok := f.values[key]; ok {
+		vc.Count++
+	} else if len(f.values) < maxTrackedValues {
+		f.values[key] = &ValueCount{Value: rawToInterface(value), Count: 1}
+	}
+
+	family, num, str, ok := sortKey(value)
+	if !ok {
+		return
+	}
+	r := f.ranges[family]
+	if r == nil {
+		f.ranges[family] = &valueRange{min: value, max: value, minKey: num, maxKey: num, minStr: str, maxStr: str}
+		return
+	}
+	if (family == "string" && str < r.minStr) || (family != "string" && num < r.minKey) {
+		r.min, r.minKey, r.minStr = value, num, str
+	}
+	if (family == "string" && str > r.maxStr) || (family != "string" && num > r.maxKey) {
+		r.max, r.maxKey, r.maxStr = value, num, str
+	}
+}
+
+// finish 计算出现比例、基数、取值范围和高频值
+func (f *FieldStats) finish(sampled int64) {
+	if sampled > 0 {
+		f.Presence = float64(f.Count) * 100 / float64(sampled)
+	}
+	f.Cardinality = int64(len(f.distinct))
+	f.Unique = f.occurrences > 1 && f.Cardinality == f.occurrences
+	if len(f.ArrayTypes) == 0 {
+		f.ArrayTypes = nil
+	}
+
+	if family, ok := rangeFamily(f.DominantType()); ok {
+		if r := f.ranges[family]; r != nil {
+			f.Min = rawToInterface(r.min)
+			f.Max = rawToInterface(r.max)
+		}
+	}
+
+	var top []ValueCount
+	for _, vc := range f.values {
+		if vc.Count > 1 {
+			top = append(top, *vc)
+		}
+	}
+	sort.Slice(top, func(i, k int) bool { return top[i].Count > top[k].Count })
+	if len(top) > topValuesLimit {
+		top = top[:topValuesLimit]
+	}
+	f.TopValues = top
+}
+
+// sortKey 将可比较的值转换为排序键，数值类型统一归为number
+func sortKey(value bson.RawValue) (family string, num float64, str string, ok bool) {
+	switch value.Type {
+	case bsontype.Double:
+		return "number", value.Double(), "", true
+	case bsontype.Int32:
+		return "number", float64(value.Int32()), "", true
+	case bsontype.Int64:
+		return "number", float64(value.Int64()), "", true
+	case bsontype.Decimal128:
+		f, err := strconv.ParseFloat(value.Decimal128().String(), 64)
+		return "number", f, "", err == nil
+	case bsontype.String:
+		return "string", 0, value.StringValue(), true
+	case bsontype.DateTime:
+		return "date", float64(value.DateTime()), "", true
+	case bsontype.Timestamp:
+		t, i := value.Timestamp()
+		return "timestamp", float64(t)*4294967296 + float64(i), "", true
+	}
+	return "", 0, "", false
+}
+
+// rangeFamily 根据类型别名判断取值范围的分组
+func rangeFamily(alias string) (string, bool) {
+	switch alias {
+	case "double", "int", "long", "decimal":
+		return "number", true
+	case "string", "date", "timestamp":
+		return alias, true
+	}
+	return "", false
+}
+
+// rawToInterface 将原始BSON值解码为Go值，ObjectID转为十六进制字符串以便前端展示
+func rawToInterface(value bson.RawValue) interface{} {
+	var v interface{}
+	if err := value.Unmarshal(&v); err != nil {
+		return value.String()
+	}
+	if oid, ok := v.(primitive.ObjectID); ok {
+		return oid.Hex()
+	}
+	return v
+}
+
+// TypeAlias 返回BSON类型在$type查询中使用的别名
+func TypeAlias(t bsontype.Type) string {
+	switch t {
+	case bsontype.Double:
+		return "double"
+	case bsontype.String:
+		return "string"
+	case bsontype.EmbeddedDocument:
+		return "object"
+	case bsontype.Array:
+		return "array"
+	case bsontype.Binary:
+		return "binData"
+	case bsontype.Undefined:
+		return "undefined"
+	case bsontype.ObjectID:
+		return "objectId"
+	case bsontype.Boolean:
+		return "bool"
+	case bsontype.DateTime:
+		return "date"
+	case bsontype.Null:
+		return "null"
+	case bsontype.Regex:
+		return "regex"
+	case bsontype.DBPointer:
+		return "dbPointer"
+	case bsontype.JavaScript:
+		return "javascript"
+	case bsontype.Symbol:
+		return "symbol"
+	case bsontype.CodeWithScope:
+		return "javascriptWithScope"
+	case bsontype.Int32:
+		return "int"
+	case bsontype.Timestamp:
+		return "timestamp"
+	case bsontype.Int64:
+		return "long"
+	case bsontype.Decimal128:
+		return "decimal"
+	case bsontype.MinKey:
+		return "minKey"
+	case bsontype.MaxKey:
+		return "maxKey"
+	}
+	return fmt.Sprintf("unknown(%d)", byte(t))
+}
+
+// JSONSchema 根据结构分析结果生成JSON Schema。
+// mongo为true时生成MongoDB $jsonSchema（使用bsonType），否则生成标准JSON Schema（使用type）；
+// 出现比例为100%的字段列为required
+func (a *SchemaAnalysis) JSONSchema(mongo bool) bson.M {
+	children := make(map[string][]*FieldStats)
+	for _, field := range a.Fields {
+		parent := ""
+		if i := strings.LastIndex(field.Path, "."); i >= 0 {
+			parent = field.Path[:i]
+		}
+		children[parent] = append(children[parent], field)
+	}
+
+	schema := objectSchema("", children, mongo, a.Sampled)
+	if !mongo {
+		schema["$schema"] = "http://json-schema.org/draft-07/schema#"
+		schema["title"] = a.Database + "." + a.Collection
+	}
+	return schema
+}
+
+// objectSchema 生成子文档的schema，parentCount为父路径出现的文档数，用于判断必填字段
+func objectSchema(path string, children map[string][]*FieldStats, mongo bool, parentCount int64) bson.M {
+	properties := bson.M{}
+	var required []string
+	for _, field := range children[path] {
+		name := field.Path[strings.LastIndex(field.Path, ".")+1:]
+		properties[name] = fieldSchema(field, children, mongo)
+		if parentCount > 0 && field.Count == parentCount {
+			required = append(required, name)
+		}
+	}
+
+	schema := bson.M{typeKey(mongo): "object", "properties": properties}
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	return schema
+}
+
+// fieldSchema 生成单个字段的schema
+func fieldSchema(field *FieldStats, children map[string][]*FieldStats, mongo bool) bson.M {
+	types := sortedTypes(field.Types)
+	schema := bson.M{}
+	setTypes(schema, types, mongo)
+
+	for _, t := range types {
+		switch t {
+		case "object":
+			sub := objectSchema(field.Path, children, mongo, field.Types["object"])
+			schema["properties"] = sub["properties"]
+			if req, ok := sub["required"]; ok {
+				schema["required"] = req
+			}
+		case "array":
+			items := bson.M{}
+			itemTypes := sortedTypes(field.ArrayTypes)
+			setTypes(items, itemTypes, mongo)
+			for _, it := range itemTypes {
+				if it == "object" {
+					sub := objectSchema(field.Path, children, mongo, 0)
+					items["properties"] = sub["properties"]
+				}
+			}
+			if len(itemTypes) > 0 {
+				schema["items"] = items
+			}
+		}
+	}
+
+	if !mongo && len(types) == 1 {
+		switch types[0] {
+		case "date":
+			schema["format"] = "date-time"
+		case "objectId":
+			schema["pattern"] = "^[0-9a-fA-F]{24}$"
+		}
+	}
+	return schema
+}
+
+// setTypes 设置schema的类型，多种类型时使用数组
+func setTypes(schema bson.M, types []string, mongo bool) {
+	var names []string
+	seen := make(map[string]bool)
+	for _, t := range types {
+		name := t
+		if !mongo {
+			name = jsonType(t)
+		}
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	switch len(names) {
+	case 0:
+	case 1:
+		schema[typeKey(mongo)] = names[0]
+	default:
+		schema[typeKey(mongo)] = names
+	}
+}
+
+func typeKey(mongo bool) string {
+	if mongo {
+		return "bsonType"
+	}
+	return "type"
+}
+
+// jsonType 将BSON类型映射为标准JSON Schema类型
+func jsonType(alias string) string {
+	switch alias {
+	case "double", "decimal":
+		return "number"
+	case "int", "long":
+		return "integer"
+	case "bool":
+		return "boolean"
+	case "object", "array", "null", "string":
+		return alias
+	}
+	return "string"
+}
+
+// sortedTypes 按出现次数降序排列类型
+func sortedTypes(types map[string]int64) []string {
+	var list []string
+	for t := range types {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, k int) bool {
+		if types[list[i]] != types[list[k]] {
+			return types[list[i]] > types[list[k]]
+		}
+		return list[i] < list[k]
+	})
+	return list
+}
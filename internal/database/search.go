@@ -0,0 +1,331 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// 搜索方式
+const (
+	// SearchExact 字符串完全相等（ObjectId按十六进制字符串比较）
+	SearchExact = "exact"
+	// SearchRegex 正则表达式（Go语法）匹配字符串和ObjectId的十六进制字符串
+	SearchRegex = "regex"
+	// SearchObjectID 与ObjectId相等，或字符串等于其十六进制形式
+	SearchObjectID = "objectId"
+)
+
+// searchValuePreview 匹配结果中值的最大字符数
+const searchValuePreview = 200
+
+// systemDatabases 搜索全部数据库时跳过的系统数据库
+var systemDatabases = map[string]bool{"admin": true, "local": true, "config": true}
+
+// SearchOptions 全局搜索条件，Limit为每个集合最多扫描的文档数，TimeBudget为每个集合的扫描时间上限，
+// 达到MaxMatches个匹配后停止搜索；各上限为0表示不限制
+type SearchOptions struct {
+	Mode       string
+	Value      string
+	IgnoreCase bool
+	Limit      int64
+	TimeBudget time.Duration
+	MaxMatches int
+}
+
+// Namespace 数据库中的一个集合
+type Namespace struct {
+	Database   string `json:"database"`
+	Collection string `json:"collection"`
+}
+
+// String 返回 db.collection 形式的名称
+func (n Namespace) String() string {
+	return n.Database + "." + n.Collection
+}
+
+// SearchMatch 一个匹配：文档的_id（canonical Extended JSON）和匹配的字段路径（数组下标以数字表示）
+type SearchMatch struct {
+	Database   string          `json:"database"`
+	Collection string          `json:"collection"`
+	ID         json.RawMessage `json:"id"`
+	Path       string          `json:"path"`
+	Value      string          `json:"value"`
+}
+
+// SearchCollection 一个集合的扫描情况，Partial为 limit 或 time 表示因达到文档数或时间上限未扫描完
+type SearchCollection struct {
+	Database   string `json:"database"`
+	Collection string `json:"collection"`
+	Scanned    int64  `json:"scanned"`
+	Matches    int    `json:"matches"`
+	Partial    string `json:"partial,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// SearchResult 搜索结果，只记录匹配数，匹配本身通过onMatch交给调用方；Truncated表示达到匹配数上限后提前结束
+type SearchResult struct {
+	Matches     int                `json:"matches"`
+	Collections []SearchCollection `json:"collections"`
+	Truncated   bool               `json:"truncated"`
+}
+
+// Validate 检查搜索条件，正则表达式和ObjectId无效时返回错误
+func (o SearchOptions) Validate() error {
+	_, err := searchMatcher(o)
+	return err
+}
+
+// searchMatcher 根据搜索方式返回匹配函数，匹配时返回用于展示的值
+func searchMatcher(o SearchOptions) (func(bson.RawValue) (string, bool), error) {
+	if o.Value == "" {
+		return nil, errors.New("search value is required")
+	}
+	// text 取字符串或ObjectId的十六进制形式，其他类型不参与匹配
+	text := func(v bson.RawValue) (string, bool) {
+		switch v.Type {
+		case bsontype.String:
+			return v.StringValue(), true
+		case bsontype.ObjectID:
+			return v.ObjectID().Hex(), true
+		}
+		return "", false
+	}
+
+	switch o.Mode {
+	case SearchExact, "":
+		return func(v bson.RawValue) (string, bool) {
+			s, ok := text(v)
+			if !ok {
+				return "", false
+			}
+			if o.IgnoreCase || v.Type == bsontype.ObjectID {
+				return s, strings.EqualFold(s, o.Value)
+			}
+			return s, s == o.Value
+		}, nil
+	case SearchRegex:
+		pattern := o.Value
+		if o.IgnoreCase {
+			pattern = "(?i)" + pattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression: %v", err)
+		}
+		return func(v bson.RawValue) (string, bool) {
+			s, ok := text(v)
+			return s, ok && re.MatchString(s)
+		}, nil
+	case SearchObjectID:
+		id, err := primitive.ObjectIDFromHex(strings.TrimSpace(o.Value))
+		if err != nil {
+			return nil, errors.New("value must be a 24-character hex ObjectId")
+		}
+		return func(v bson.RawValue) (string, bool) {
+			switch v.Type {
+			case bsontype.ObjectID:
+				return id.Hex(), v.ObjectID() == id
+			case bsontype.String:
+				return v.StringValue(), strings.EqualFold(v.StringValue(), id.Hex())
+			}
+			return "", false
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown search mode %q", o.Mode)
+}
+
+// SearchNamespaces 确定要搜索的集合：selected中的 db 表示该数据库的所有集合，db.collection 表示单个集合；
+// selected为空时搜索除系统数据库和excluded以外的所有数据库。系统集合和历史版本集合只在明确指定时搜索
+func (s *Service) SearchNamespaces(ctx context.Context, selected []string, excluded []string) ([]Namespace, error) {
+	ctx, cancel := s.withTimeout(ctx, s.timeouts.Read)
+	defer cancel()
+
+	skip := make(map[string]bool)
+	for _, name := range excluded {
+		skip[name] = true
+	}
+
+	var namespaces []Namespace
+	seen := make(map[Namespace]bool)
+	add := func(ns Namespace) {
+		if !seen[ns] {
+			seen[ns] = true
+			namespaces = append(namespaces, ns)
+		}
+	}
+	addDatabase := func(dbName string) error {
+		collections, err := s.probeCollections(ctx, dbName)
+		if err != nil {
+			return err
+		}
+		for _, collection := range collections {
+			add(Namespace{Database: dbName, Collection: collection})
+		}
+		return nil
+	}
+
+	if len(selected) == 0 {
+		databases, err := s.client.ListDatabaseNames(ctx, bson.M{})
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(databases)
+		for _, dbName := range databases {
+			if systemDatabases[dbName] || skip[dbName] {
+				continue
+			}
+			if err := addDatabase(dbName); err != nil {
+				return nil, err
+			}
+		}
+		return namespaces, nil
+	}
+
+	for _, name := range selected {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if dbName, collection, ok := strings.Cut(name, "."); ok && collection != "" {
+			add(Namespace{Database: dbName, Collection: collection})
+			continue
+		}
+		if err := addDatabase(strings.TrimSuffix(name, ".")); err != nil {
+			return nil, err
+		}
+	}
+	return namespaces, nil
+}
+
+// Search 依次扫描各集合，在所有字符串和ObjectId字段中查找与条件匹配的值。每找到一个匹配调用onMatch，
+// 每开始扫描一个集合调用progress；ctx被取消时返回已扫描部分的结果和ctx的错误
+func (s *Service) Search(ctx context.Context, namespaces []Namespace, opts SearchOptions, onMatch func(SearchMatch), progress func(done, total int64, current Namespace)) (*SearchResult, error) {
+	match, err := searchMatcher(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SearchResult{Collections: []SearchCollection{}}
+	total := int64(len(namespaces))
+	for i, ns := range namespaces {
+		if progress != nil {
+			progress(int64(i), total, ns)
+		}
+		stats, err := s.searchCollection(ctx, ns, opts, match, func(m SearchMatch) bool {
+			result.Matches++
+			if onMatch != nil {
+				onMatch(m)
+			}
+			return opts.MaxMatches <= 0 || result.Matches < opts.MaxMatches
+		})
+		result.Collections = append(result.Collections, stats)
+		if err != nil {
+			return result, err
+		}
+		if opts.MaxMatches > 0 && result.Matches >= opts.MaxMatches {
+			result.Truncated = true
+			break
+		}
+	}
+	if progress != nil && !result.Truncated {
+		progress(total, total, Namespace{})
+	}
+	return result, nil
+}
+
+// searchCollection 扫描一个集合；add返回false时停止。只有ctx被取消时返回错误，
+// 集合自身的错误（如没有权限）记录在结果中，继续搜索其他集合
+func (s *Service) searchCollection(ctx context.Context, ns Namespace, opts SearchOptions, match func(bson.RawValue) (string, bool), add func(SearchMatch) bool) (SearchCollection, error) {
+	stats := SearchCollection{Database: ns.Database, Collection: ns.Collection}
+	scanCtx, cancel := s.withTimeout(ctx, opts.TimeBudget)
+	defer cancel()
+
+	findOptions := options.Find().SetBatchSize(500)
+	if opts.Limit > 0 {
+		findOptions.SetLimit(opts.Limit)
+	}
+	cursor, err := s.client.Database(ns.Database).Collection(ns.Collection).Find(scanCtx, bson.D{}, findOptions)
+	if err == nil {
+		defer cursor.Close(ctx)
+		for cursor.Next(scanCtx) {
+			stats.Scanned++
+			var id json.RawMessage
+			more := walkSearch(cursor.Current, "", match, func(path, value string) bool {
+				if id == nil {
+					id = rawExtJSON(cursor.Current.Lookup("_id"))
+				}
+				if runes := []rune(value); len(runes) > searchValuePreview {
+					value = string(runes[:searchValuePreview]) + "…"
+				}
+				stats.Matches++
+				return add(SearchMatch{Database: ns.Database, Collection: ns.Collection, ID: id, Path: path, Value: value})
+			})
+			if !more {
+				break
+			}
+		}
+		err = cursor.Err()
+	}
+
+	switch {
+	case ctx.Err() != nil:
+		return stats, ctx.Err()
+	case err != nil && errors.Is(err, context.DeadlineExceeded):
+		stats.Partial = "time"
+	case err != nil:
+		stats.Error = err.Error()
+	case opts.Limit > 0 && stats.Scanned >= opts.Limit:
+		stats.Partial = "limit"
+	}
+	return stats, nil
+}
+
+// walkSearch 遍历文档中的所有值，对匹配的值调用found；found返回false时停止遍历
+func walkSearch(doc bson.Raw, prefix string, match func(bson.RawValue) (string, bool), found func(path, value string) bool) bool {
+	elements, err := doc.Elements()
+	if err != nil {
+		return true
+	}
+	for _, elem := range elements {
+		path := elem.Key()
+		if prefix != "" {
+			path = prefix + "." + path
+		}
+		value := elem.Value()
+		switch value.Type {
+		case bsontype.EmbeddedDocument:
+			if !walkSearch(value.Document(), path, match, found) {
+				return false
+			}
+		case bsontype.Array:
+			if !walkSearch(bson.Raw(value.Array()), path, match, found) {
+				return false
+			}
+		default:
+			if text, ok := match(value); ok && !found(path, text) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// rawExtJSON 将BSON值编码为canonical Extended JSON
+func rawExtJSON(value bson.RawValue) json.RawMessage {
+	var v interface{}
+	if err := value.Unmarshal(&v); err != nil {
+		return json.RawMessage("null")
+	}
+	return CanonicalExtJSONValue(v)
+}
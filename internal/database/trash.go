@@ -0,0 +1,302 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// 回收站条目类型
+const (
+	TrashDocument   = "document"
+	TrashCollection = "collection"
+)
+
+// trashEntriesCollection 回收站数据库中保存条目元数据的集合
+const trashEntriesCollection = "entries"
+
+// ErrRestoreConflict 恢复目标已存在同名集合或相同_id的文档
+var ErrRestoreConflict = errors.New("restore target already exists")
+
+// TrashEntry 回收站条目：被删除的单个文档，或被整体移入回收站数据库的集合
+type TrashEntry struct {
+	ID              primitive.ObjectID `bson:"_id"`
+	Kind            string             `bson:"kind"`
+	Database        string             `bson:"database"`
+	Collection      string             `bson:"collection"`
+	DocumentID      interface{}        `bson:"documentId,omitempty"`
+	Document        bson.Raw           `bson:"document,omitempty"`
+	TrashCollection string             `bson:"trashCollection,omitempty"`
+	DocumentCount   int64              `bson:"documentCount,omitempty"`
+	DeletedBy       string             `bson:"deletedBy"`
+	DeletedAt       time.Time          `bson:"deletedAt"`
+}
+
+// MarshalJSON 文档内容以relaxed Extended JSON输出
+func (e TrashEntry) MarshalJSON() ([]byte, error) {
+	out := struct {
+		ID              string          `json:"id"`
+		Kind            string          `json:"kind"`
+		Database        string          `json:"database"`
+		Collection      string          `json:"collection"`
+		DocumentID      json.RawMessage `json:"documentId,omitempty"`
+		Document        json.RawMessage `json:"document,omitempty"`
+		TrashCollection string          `json:"trashCollection,omitempty"`
+		DocumentCount   int64           `json:"documentCount,omitempty"`
+		DeletedBy       string          `json:"deletedBy"`
+		DeletedAt       int64           `json:"deletedAt"`
+	}{
+		ID:              e.ID.Hex(),
+		Kind:            e.Kind,
+		Database:        e.Database,
+		Collection:      e.Collection,
+		TrashCollection: e.TrashCollection,
+		DocumentCount:   e.DocumentCount,
+		DeletedBy:       e.DeletedBy,
+		DeletedAt:       e.DeletedAt.Unix(),
+	}
+	if e.DocumentID != nil {
+		out.DocumentID = ExtJSONValue(e.DocumentID)
+	}
+	if e.Document != nil {
+		out.Document = ExtJSONValue(e.Document)
+	}
+	return json.Marshal(out)
+}
+
+// Trash 回收站，删除的数据保存在独立的数据库中
+type Trash struct {
+	service  *Service
+	database string
+}
+
+// Trash 返回使用指定数据库的回收站
+func (s *Service) Trash(dbName string) *Trash {
+	return &Trash{service: s, database: dbName}
+}
+
+// Database 回收站所在的数据库
+func (t *Trash) Database() string {
+	return t.database
+}
+
+func (t *Trash) entries() *mongo.Collection {
+	return t.service.client.Database(t.database).Collection(trashEntriesCollection)
+}
+
+// TrashDocument 将文档复制到回收站后再删除；original的含义同DeleteDocument，删除失败时撤销回收站条目
+func (t *Trash) TrashDocument(ctx context.Context, dbName, collectionName, id string, original bson.D, deletedBy string) (*TrashEntry, error) {
+	document, err := bson.Marshal(original)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &TrashEntry{
+		ID:         primitive.NewObjectID(),
+		Kind:       TrashDocument,
+		Database:   dbName,
+		Collection: collectionName,
+		Document:   document,
+		DeletedBy:  deletedBy,
+		DeletedAt:  time.Now().UTC().Truncate(time.Millisecond),
+	}
+	if value, err := bson.Raw(document).LookupErr("_id"); err == nil {
+		entry.DocumentID = value
+	}
+
+	if err := t.insertEntry(ctx, entry); err != nil {
+		return nil, err
+	}
+	if err := t.service.DeleteDocument(ctx, dbName, collectionName, id, original); err != nil {
+		t.removeEntry(context.WithoutCancel(ctx), entry.ID)
+		return nil, err
+	}
+	return entry, nil
+}
+
+// TrashCollection 将集合重命名到回收站数据库中，保留索引和全部文档。跨数据库重命名需要复制全部数据，
+// 耗时与集合大小有关，不使用管理操作超时，应在后台任务中调用
+func (t *Trash) TrashCollection(ctx context.Context, dbName, collectionName, deletedBy string) (*TrashEntry, error) {
+	countCtx, cancel := t.service.withTimeout(ctx, t.service.timeouts.Read)
+	count, err := t.service.client.Database(dbName).Collection(collectionName).EstimatedDocumentCount(countCtx)
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &TrashEntry{
+		ID:            primitive.NewObjectID(),
+		Kind:          TrashCollection,
+		Database:      dbName,
+		Collection:    collectionName,
+		DocumentCount: count,
+		DeletedBy:     deletedBy,
+		DeletedAt:     time.Now().UTC().Truncate(time.Millisecond),
+	}
+	entry.TrashCollection = "c_" + entry.ID.Hex()
+
+	if err := t.renameCollection(ctx, dbName, collectionName, t.database, entry.TrashCollection); err != nil {
+		return nil, err
+	}
+	if err := t.insertEntry(ctx, entry); err != nil {
+		// 无法记录条目时把集合移回原处，避免出现无法恢复的孤立集合
+		t.renameCollection(context.WithoutCancel(ctx), t.database, entry.TrashCollection, dbName, collectionName)
+		return nil, err
+	}
+	return entry, nil
+}
+
+// List 按删除时间倒序列出回收站条目，dbName不为空时只列出该数据库的条目
+func (t *Trash) List(ctx context.Context, dbName string, limit int64) ([]TrashEntry, error) {
+	ctx, cancel := t.service.withTimeout(ctx, t.service.timeouts.Read)
+	defer cancel()
+
+	filter := bson.D{}
+	if dbName != "" {
+		filter = append(filter, bson.E{Key: "database", Value: dbName})
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "deletedAt", Value: -1}}).SetLimit(limit)
+
+	cursor, err := t.entries().Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	entries := []TrashEntry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Get 获取回收站条目
+func (t *Trash) Get(ctx context.Context, id string) (*TrashEntry, error) {
+	ctx, cancel := t.service.withTimeout(ctx, t.service.timeouts.Read)
+	defer cancel()
+
+	objectID, err := toObjectID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var entry TrashEntry
+	if err := t.entries().FindOne(ctx, bson.M{"_id": objectID}).Decode(&entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// Restore 将条目恢复到原来的位置，目标已存在时返回ErrRestoreConflict
+func (t *Trash) Restore(ctx context.Context, id string) (*TrashEntry, error) {
+	entry, err := t.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	switch entry.Kind {
+	case TrashDocument:
+		writeCtx, cancel := t.service.withTimeout(ctx, t.service.timeouts.Write)
+		_, err = t.service.client.Database(entry.Database).Collection(entry.Collection).InsertOne(writeCtx, entry.Document)
+		cancel()
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrRestoreConflict
+		}
+	case TrashCollection:
+		err = t.renameCollection(ctx, t.database, entry.TrashCollection, entry.Database, entry.Collection)
+		if isNamespaceExists(err) {
+			return nil, ErrRestoreConflict
+		}
+	default:
+		return nil, fmt.Errorf("unknown trash entry kind %q", entry.Kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return entry, t.removeEntry(ctx, entry.ID)
+}
+
+// Purge 彻底删除条目，集合条目同时删除回收站中的集合
+func (t *Trash) Purge(ctx context.Context, id string) error {
+	entry, err := t.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	return t.purge(ctx, entry)
+}
+
+// Sweep 彻底删除在before之前删除的条目，返回清理的条目数
+func (t *Trash) Sweep(ctx context.Context, before time.Time) (int, error) {
+	findCtx, cancel := t.service.withTimeout(ctx, t.service.timeouts.Read)
+	defer cancel()
+
+	cursor, err := t.entries().Find(findCtx, bson.M{"deletedAt": bson.M{"$lt": before}},
+		options.Find().SetProjection(bson.M{"document": 0}))
+	if err != nil {
+		return 0, err
+	}
+	var expired []TrashEntry
+	if err := cursor.All(findCtx, &expired); err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for i := range expired {
+		if err := t.purge(ctx, &expired[i]); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+func (t *Trash) purge(ctx context.Context, entry *TrashEntry) error {
+	if entry.Kind == TrashCollection {
+		dropCtx, cancel := t.service.withTimeout(ctx, t.service.timeouts.Admin)
+		err := t.service.client.Database(t.database).Collection(entry.TrashCollection).Drop(dropCtx)
+		cancel()
+		if err != nil {
+			return err
+		}
+	}
+	return t.removeEntry(ctx, entry.ID)
+}
+
+func (t *Trash) insertEntry(ctx context.Context, entry *TrashEntry) error {
+	ctx, cancel := t.service.withTimeout(ctx, t.service.timeouts.Write)
+	defer cancel()
+
+	_, err := t.entries().InsertOne(ctx, entry)
+	return err
+}
+
+func (t *Trash) removeEntry(ctx context.Context, id primitive.ObjectID) error {
+	ctx, cancel := t.service.withTimeout(ctx, t.service.timeouts.Write)
+	defer cancel()
+
+	_, err := t.entries().DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+// renameCollection 跨数据库重命名集合，目标已存在时失败；只受ctx控制，不设超时
+func (t *Trash) renameCollection(ctx context.Context, fromDB, fromCollection, toDB, toCollection string) error {
+	command := bson.D{
+		{Key: "renameCollection", Value: fromDB + "." + fromCollection},
+		{Key: "to", Value: toDB + "." + toCollection},
+	}
+	return t.service.client.Database("admin").RunCommand(ctx, command).Err()
+}
+
+// isNamespaceExists 判断是否为目标集合已存在的错误
+func isNamespaceExists(err error) bool {
+	var cmdErr mongo.CommandError
+	return errors.As(err, &cmdErr) && cmdErr.Code == 48
+}
@@ -0,0 +1,428 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrUnsupportedUpdate 更新语句无法在本地模拟（如位置运算符、聚合管道），此时预览不提供差异
+var ErrUnsupportedUpdate = errors.New("update cannot be simulated locally")
+
+// ApplyUpdate 在本地对文档模拟执行更新运算符，返回更新后的副本，原文档不变。
+// 支持 $set $unset $inc $mul $min $max $rename $currentDate $push $addToSet $pull $pop，
+// 不支持位置运算符和带条件的$pull
+func ApplyUpdate(doc bson.D, update bson.D) (bson.D, error) {
+	result, _ := copyValue(doc).(bson.D)
+
+	for _, op := range update {
+		fields, ok := asDocument(op.Value)
+		if !ok {
+			return nil, fmt.Errorf("%s requires a document", op.Key)
+		}
+		for _, field := range fields {
+			parts := strings.Split(field.Key, ".")
+			for _, part := range parts {
+				if part == "$" || strings.HasPrefix(part, "$[") {
+					return nil, fmt.Errorf("%w: positional operator in %q", ErrUnsupportedUpdate, field.Key)
+				}
+			}
+
+			var err error
+			result, err = applyOperator(result, op.Key, parts, field.Value)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return result, nil
+}
+
+func applyOperator(doc bson.D, operator string, parts []string, arg interface{}) (bson.D, error) {
+	current, exists := getIn(doc, parts)
+
+	switch operator {
+	case "$set":
+		return setPath(doc, parts, copyValue(arg))
+	case "$unset":
+		return unsetPath(doc, parts), nil
+	case "$setOnInsert":
+		return doc, nil
+	case "$inc":
+		if !exists {
+			return setPath(doc, parts, arg)
+		}
+		sum, err := arithmetic(current, arg, false)
+		if err != nil {
+			return nil, err
+		}
+		return setPath(doc, parts, sum)
+	case "$mul":
+		if !exists {
+			zero, _ := arithmetic(arg, int32(0), true)
+			return setPath(doc, parts, zero)
+		}
+		product, err := arithmetic(current, arg, true)
+		if err != nil {
+			return nil, err
+		}
+		return setPath(doc, parts, product)
+	case "$min", "$max":
+		if exists {
+			cmp, ok := compareValues(arg, current)
+			if !ok {
+				return nil, fmt.Errorf("%w: cannot compare values for %s", ErrUnsupportedUpdate, operator)
+			}
+			if (operator == "$min" && cmp >= 0) || (operator == "$max" && cmp <= 0) {
+				return doc, nil
+			}
+		}
+		return setPath(doc, parts, copyValue(arg))
+	case "$rename":
+		target, ok := arg.(string)
+		if !ok {
+			return nil, fmt.Errorf("$rename target must be a string")
+		}
+		if !exists {
+			return doc, nil
+		}
+		doc = unsetPath(doc, parts)
+		return setPath(doc, strings.Split(target, "."), current)
+	case "$currentDate":
+		var value interface{} = primitive.NewDateTimeFromTime(time.Now())
+		if spec, ok := asDocument(arg); ok {
+			for _, e := range spec {
+				if e.Key == "$type" && e.Value == "timestamp" {
+					value = primitive.Timestamp{T: uint32(time.Now().Unix())}
+				}
+			}
+		}
+		return setPath(doc, parts, value)
+	case "$push", "$addToSet":
+		return applyArrayAdd(doc, operator, parts, current, exists, arg)
+	case "$pull":
+		if !exists {
+			return doc, nil
+		}
+		arr, ok := current.(bson.A)
+		if !ok {
+			return nil, fmt.Errorf("cannot apply $pull to a non-array value")
+		}
+		if cond, ok := asDocument(arg); ok && hasOperatorKeys(cond) {
+			return nil, fmt.Errorf("%w: conditional $pull", ErrUnsupportedUpdate)
+		}
+		kept := bson.A{}
+		for _, item := range arr {
+			if !ValuesEqual(item, arg) {
+				kept = append(kept, item)
+			}
+		}
+		return setPath(doc, parts, kept)
+	case "$pop":
+		if !exists {
+			return doc, nil
+		}
+		arr, ok := current.(bson.A)
+		if !ok {
+			return nil, fmt.Errorf("cannot apply $pop to a non-array value")
+		}
+		if len(arr) == 0 {
+			return doc, nil
+		}
+		if n, _ := toFloat(arg); n < 0 {
+			return setPath(doc, parts, append(bson.A{}, arr[1:]...))
+		}
+		return setPath(doc, parts, append(bson.A{}, arr[:len(arr)-1]...))
+	}
+
+	return nil, fmt.Errorf("%w: operator %s", ErrUnsupportedUpdate, operator)
+}
+
+// applyArrayAdd 处理$push和$addToSet，支持$each、$position和$slice修饰符
+func applyArrayAdd(doc bson.D, operator string, parts []string, current interface{}, exists bool, arg interface{}) (bson.D, error) {
+	arr := bson.A{}
+	if exists {
+		existing, ok := current.(bson.A)
+		if !ok {
+			return nil, fmt.Errorf("cannot apply %s to a non-array value", operator)
+		}
+		arr = append(arr, existing...)
+	}
+
+	items := bson.A{arg}
+	position, slice := -1, 0
+	hasSlice := false
+	if spec, ok := asDocument(arg); ok && len(spec) > 0 && spec[0].Key == "$each" {
+		items = nil
+		for _, e := range spec {
+			switch e.Key {
+			case "$each":
+				each, ok := e.Value.(bson.A)
+				if !ok {
+					return nil, fmt.Errorf("$each requires an array")
+				}
+				items = each
+			case "$position":
+				n, _ := toFloat(e.Value)
+				position = int(n)
+			case "$slice":
+				n, _ := toFloat(e.Value)
+				slice, hasSlice = int(n), true
+			default:
+				return nil, fmt.Errorf("%w: modifier %s", ErrUnsupportedUpdate, e.Key)
+			}
+		}
+	}
+
+	if operator == "$addToSet" {
+		for _, item := range items {
+			found := false
+			for _, existing := range arr {
+				if ValuesEqual(existing, item) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				arr = append(arr, copyValue(item))
+			}
+		}
+		return setPath(doc, parts, arr)
+	}
+
+	if position < 0 || position > len(arr) {
+		position = len(arr)
+	}
+	merged := append(bson.A{}, arr[:position]...)
+	for _, item := range items {
+		merged = append(merged, copyValue(item))
+	}
+	merged = append(merged, arr[position:]...)
+
+	if hasSlice {
+		switch {
+		case slice >= 0 && slice < len(merged):
+			merged = merged[:slice]
+		case slice < 0 && -slice < len(merged):
+			merged = merged[len(merged)+slice:]
+		}
+	}
+	return setPath(doc, parts, merged)
+}
+
+// hasOperatorKeys 文档中是否包含$开头的键
+func hasOperatorKeys(doc bson.D) bool {
+	for _, e := range doc {
+		if strings.HasPrefix(e.Key, "$") {
+			return true
+		}
+	}
+	return false
+}
+
+// getIn 按路径读取值，数字路径段用于访问数组元素
+func getIn(value interface{}, parts []string) (interface{}, bool) {
+	if len(parts) == 0 {
+		return value, true
+	}
+	switch v := value.(type) {
+	case bson.D:
+		for _, e := range v {
+			if e.Key == parts[0] {
+				return getIn(e.Value, parts[1:])
+			}
+		}
+	case bson.A:
+		if i, err := strconv.Atoi(parts[0]); err == nil && i >= 0 && i < len(v) {
+			return getIn(v[i], parts[1:])
+		}
+	case bson.M:
+		if child, ok := v[parts[0]]; ok {
+			return getIn(child, parts[1:])
+		}
+	}
+	return nil, false
+}
+
+// setPath 按路径写入值，中间缺失的子文档会被创建
+func setPath(doc bson.D, parts []string, value interface{}) (bson.D, error) {
+	result, err := setIn(doc, parts, value)
+	if err != nil {
+		return nil, err
+	}
+	return result.(bson.D), nil
+}
+
+func setIn(container interface{}, parts []string, value interface{}) (interface{}, error) {
+	if len(parts) == 0 {
+		return value, nil
+	}
+	switch v := container.(type) {
+	case nil:
+		child, err := setIn(nil, parts[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		return bson.D{{Key: parts[0], Value: child}}, nil
+	case bson.M:
+		return setIn(mapToD(v), parts, value)
+	case bson.D:
+		for i, e := range v {
+			if e.Key == parts[0] {
+				child, err := setIn(e.Value, parts[1:], value)
+				if err != nil {
+					return nil, err
+				}
+				v[i].Value = child
+				return v, nil
+			}
+		}
+		child, err := setIn(nil, parts[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		return append(v, bson.E{Key: parts[0], Value: child}), nil
+	case bson.A:
+		index, err := strconv.Atoi(parts[0])
+		if err != nil || index < 0 {
+			return nil, fmt.Errorf("cannot create field %q in array", parts[0])
+		}
+		for len(v) <= index {
+			v = append(v, nil)
+		}
+		child, err := setIn(v[index], parts[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		v[index] = child
+		return v, nil
+	}
+	return nil, fmt.Errorf("cannot create field %q in a non-document value", parts[0])
+}
+
+// unsetPath 按路径删除字段，数组元素会被置为null（与MongoDB行为一致）
+func unsetPath(doc bson.D, parts []string) bson.D {
+	result, _ := unsetIn(doc, parts).(bson.D)
+	return result
+}
+
+func unsetIn(container interface{}, parts []string) interface{} {
+	switch v := container.(type) {
+	case bson.D:
+		for i, e := range v {
+			if e.Key != parts[0] {
+				continue
+			}
+			if len(parts) == 1 {
+				return append(v[:i:i], v[i+1:]...)
+			}
+			v[i].Value = unsetIn(e.Value, parts[1:])
+			return v
+		}
+	case bson.A:
+		index, err := strconv.Atoi(parts[0])
+		if err != nil || index < 0 || index >= len(v) {
+			return v
+		}
+		if len(parts) == 1 {
+			v[index] = nil
+		} else {
+			v[index] = unsetIn(v[index], parts[1:])
+		}
+		return v
+	}
+	return container
+}
+
+// copyValue 深拷贝文档和数组
+func copyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case bson.D:
+		out := make(bson.D, len(v))
+		for i, e := range v {
+			out[i] = bson.E{Key: e.Key, Value: copyValue(e.Value)}
+		}
+		return out
+	case bson.M:
+		return copyValue(mapToD(v))
+	case map[string]interface{}:
+		return copyValue(mapToD(v))
+	case bson.A:
+		out := make(bson.A, len(v))
+		for i, item := range v {
+			out[i] = copyValue(item)
+		}
+		return out
+	case []interface{}:
+		return copyValue(bson.A(v))
+	}
+	return value
+}
+
+// arithmetic 数值加法或乘法，结果类型遵循MongoDB的提升规则：int → long → double
+func arithmetic(a, b interface{}, multiply bool) (interface{}, error) {
+	if _, ok := a.(primitive.Decimal128); ok {
+		return nil, fmt.Errorf("%w: decimal arithmetic", ErrUnsupportedUpdate)
+	}
+	if _, ok := b.(primitive.Decimal128); ok {
+		return nil, fmt.Errorf("%w: decimal arithmetic", ErrUnsupportedUpdate)
+	}
+	fa, okA := toFloat(a)
+	fb, okB := toFloat(b)
+	if !okA || !okB {
+		return nil, fmt.Errorf("cannot apply arithmetic to non-numeric value")
+	}
+
+	result := fa + fb
+	if multiply {
+		result = fa * fb
+	}
+
+	_, aDouble := a.(float64)
+	_, bDouble := b.(float64)
+	if aDouble || bDouble {
+		return result, nil
+	}
+	_, aLong := a.(int64)
+	_, bLong := b.(int64)
+	if !aLong && !bLong && result >= math.MinInt32 && result <= math.MaxInt32 {
+		return int32(result), nil
+	}
+	return int64(result), nil
+}
+
+// compareValues 比较数值、字符串和日期，类型不同或不可比较时ok为false
+func compareValues(a, b interface{}) (int, bool) {
+	if fa, ok := toFloat(a); ok {
+		if fb, ok := toFloat(b); ok {
+			return compareFloat(fa, fb), true
+		}
+	}
+	switch va := a.(type) {
+	case string:
+		if vb, ok := b.(string); ok {
+			return strings.Compare(va, vb), true
+		}
+	case primitive.DateTime:
+		if vb, ok := b.(primitive.DateTime); ok {
+			return compareFloat(float64(va), float64(vb)), true
+		}
+	}
+	return 0, false
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
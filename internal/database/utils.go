@@ -1,6 +1,8 @@
 package database
 
 import (
+	"encoding/json"
+
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -28,4 +30,37 @@ func parseQuery(query string) (map[string]interface{}, error) {
 	var result map[string]interface{}
 	err := bson.UnmarshalExtJSON([]byte(query), true, &result)
 	return result, err
-}
\ No newline at end of file
+}
+
+// ExtJSON 将值编码为Extended JSON，canonical为true时保留int32、int64、double的区别；值不要求是文档
+func ExtJSON(v interface{}, canonical bool) (json.RawMessage, error) {
+	data, err := bson.MarshalExtJSON(bson.D{{Key: "v", Value: v}}, canonical, false)
+	if err != nil {
+		return nil, err
+	}
+	var wrapper struct {
+		V json.RawMessage `json:"v"`
+	}
+	if err := json.Unmarshal(data, &wrapper); err != nil {
+		return nil, err
+	}
+	return wrapper.V, nil
+}
+
+// ExtJSONValue 将单个值编码为relaxed Extended JSON，无法编码时为null
+func ExtJSONValue(v interface{}) json.RawMessage {
+	data, err := ExtJSON(v, false)
+	if err != nil {
+		return json.RawMessage("null")
+	}
+	return data
+}
+
+// CanonicalExtJSONValue 将单个值编码为canonical Extended JSON，无法编码时为null
+func CanonicalExtJSONValue(v interface{}) json.RawMessage {
+	data, err := ExtJSON(v, true)
+	if err != nil {
+		return json.RawMessage("null")
+	}
+	return data
+}
@@ -0,0 +1,143 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	// ErrCollectionNotFound 集合不存在
+	ErrCollectionNotFound = errors.New("collection not found")
+	// ErrInvalidValidator 校验级别或行为取值无效
+	ErrInvalidValidator = errors.New("invalid validator settings")
+)
+
+// ValidatorInfo 集合的文档校验配置
+type ValidatorInfo struct {
+	Validator        bson.M `json:"validator" bson:"validator"`
+	ValidationLevel  string `json:"validationLevel" bson:"validationLevel"`
+	ValidationAction string `json:"validationAction" bson:"validationAction"`
+}
+
+// ValidationScan 校验规则试运行结果
+type ValidationScan struct {
+	Violations int64                    `json:"violations"`
+	Documents  []map[string]interface{} `json:"documents"`
+}
+
+// GetValidator 获取集合的校验规则，未设置时返回默认的级别和行为；集合不存在时返回ErrCollectionNotFound
+func (s *Service) GetValidator(ctx context.Context, dbName, collectionName string) (*ValidatorInfo, error) {
+	ctx, cancel := s.withTimeout(ctx, s.timeouts.Read)
+	defer cancel()
+
+	specs, err := s.client.Database(dbName).ListCollectionSpecifications(ctx, bson.M{"name": collectionName})
+	if err != nil {
+		return nil, err
+	}
+	if len(specs) == 0 {
+		return nil, ErrCollectionNotFound
+	}
+
+	info := &ValidatorInfo{
+		Validator:        bson.M{},
+		ValidationLevel:  "strict",
+		ValidationAction: "error",
+	}
+	if specs[0].Options != nil {
+		if err := bson.Unmarshal(specs[0].Options, info); err != nil {
+			return nil, err
+		}
+	}
+	if info.Validator == nil {
+		info.Validator = bson.M{}
+	}
+	return info, nil
+}
+
+// UpdateValidator 通过collMod修改集合的校验规则；集合不存在时返回ErrCollectionNotFound
+func (s *Service) UpdateValidator(ctx context.Context, dbName, collectionName string, info *ValidatorInfo) error {
+	ctx, cancel := s.withTimeout(ctx, s.timeouts.Admin)
+	defer cancel()
+
+	if err := validateValidatorInfo(info); err != nil {
+		return err
+	}
+
+	validator := info.Validator
+	if validator == nil {
+		validator = bson.M{}
+	}
+	cmd := bson.D{
+		{Key: "collMod", Value: collectionName},
+		{Key: "validator", Value: validator},
+		{Key: "validationLevel", Value: info.ValidationLevel},
+		{Key: "validationAction", Value: info.ValidationAction},
+	}
+	err := s.client.Database(dbName).RunCommand(ctx, cmd).Err()
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.Code == 26 { // NamespaceNotFound
+		return ErrCollectionNotFound
+	}
+	return err
+}
+
+// validateValidatorInfo 校验级别和行为取值
+func validateValidatorInfo(info *ValidatorInfo) error {
+	switch info.ValidationLevel {
+	case "off", "strict", "moderate":
+	default:
+		return fmt.Errorf("%w: validationLevel %q", ErrInvalidValidator, info.ValidationLevel)
+	}
+	switch info.ValidationAction {
+	case "error", "warn", "errorAndLog":
+	default:
+		return fmt.Errorf("%w: validationAction %q", ErrInvalidValidator, info.ValidationAction)
+	}
+	return nil
+}
+
+// GenerateValidator 根据采样数据生成$jsonSchema校验规则
+func (s *Service) GenerateValidator(ctx context.Context, dbName, collectionName string, sampleSize int64) (bson.M, error) {
+	analysis, err := s.AnalyzeSchema(ctx, dbName, collectionName, sampleSize)
+	if err != nil {
+		return nil, err
+	}
+	return bson.M{"$jsonSchema": analysis.JSONSchema(true)}, nil
+}
+
+// ScanValidator 试运行校验规则，统计现有文档中不满足规则的数量并返回前limit条
+func (s *Service) ScanValidator(ctx context.Context, dbName, collectionName string, validator bson.M, limit int64) (*ValidationScan, error) {
+	ctx, cancel := s.withTimeout(ctx, s.timeouts.Read)
+	defer cancel()
+
+	collection := s.client.Database(dbName).Collection(collectionName)
+
+	// 校验规则本身就是查询条件，取反即为违规文档
+	filter := bson.M{"$nor": bson.A{validator}}
+
+	count, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := collection.Find(ctx, filter, options.Find().SetLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	documents := []map[string]interface{}{}
+	if err := cursor.All(ctx, &documents); err != nil {
+		return nil, err
+	}
+
+	return &ValidationScan{
+		Violations: count,
+		Documents:  documents,
+	}, nil
+}
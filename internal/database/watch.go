@@ -0,0 +1,47 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// WatchOptions 变更流参数
+type WatchOptions struct {
+	Match        map[string]interface{} // 附加的$match条件，可为空
+	UpdateLookup bool                   // 更新事件是否返回完整文档(fullDocument: updateLookup)
+	ResumeAfter  interface{}            // 从指定的resume token之后继续
+	MaxAwaitTime time.Duration          // 单次等待新事件的最长时间
+}
+
+// Watch 打开变更流。dbName为空时监听整个集群，collectionName为空时监听整个数据库。
+// 变更流是长连接，不附加操作超时，由调用方的上下文控制生命周期
+func (s *Service) Watch(ctx context.Context, dbName, collectionName string, opts WatchOptions) (*mongo.ChangeStream, error) {
+	pipeline := mongo.Pipeline{}
+	if len(opts.Match) > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: opts.Match}})
+	}
+
+	streamOpts := options.ChangeStream()
+	if opts.UpdateLookup {
+		streamOpts.SetFullDocument(options.UpdateLookup)
+	}
+	if opts.ResumeAfter != nil {
+		streamOpts.SetResumeAfter(opts.ResumeAfter)
+	}
+	if opts.MaxAwaitTime > 0 {
+		streamOpts.SetMaxAwaitTime(opts.MaxAwaitTime)
+	}
+
+	switch {
+	case dbName == "":
+		return s.client.Watch(ctx, pipeline, streamOpts)
+	case collectionName == "":
+		return s.client.Database(dbName).Watch(ctx, pipeline, streamOpts)
+	default:
+		return s.client.Database(dbName).Collection(collectionName).Watch(ctx, pipeline, streamOpts)
+	}
+}
@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"m-db-ui/internal/jobs"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// bulkPreviewSamples 预览时返回的示例文档数
+const bulkPreviewSamples = 5
+
+// bulkPlan 已预览、等待确认执行的批量操作
+type bulkPlan struct {
+	Operation  string
+	DB         string
+	Collection string
+	Filter     bson.D
+	Update     interface{}
+	Matched    int64
+	ExpiresAt  time.Time
+}
+
+// bulkPlans 预览令牌表，令牌只能使用一次
+type bulkPlans struct {
+	plans map[string]*bulkPlan
+	mutex sync.Mutex
+}
+
+func newBulkPlans() *bulkPlans {
+	return &bulkPlans{plans: make(map[string]*bulkPlan)}
+}
+
+// add 保存预览结果并返回令牌，同时清理过期令牌
+func (p *bulkPlans) add(plan *bulkPlan) string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	token := hex.EncodeToString(buf)
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	now := time.Now()
+	for t, existing := range p.plans {
+		if now.After(existing.ExpiresAt) {
+			delete(p.plans, t)
+		}
+	}
+	p.plans[token] = plan
+	return token
+}
+
+// take 取出并作废令牌；令牌不属于dbName.collectionName时返回错误，令牌保持可用
+func (p *bulkPlans) take(token, dbName, collectionName string) (*bulkPlan, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	plan, exists := p.plans[token]
+	if !exists {
+		return nil, fmt.Errorf("preview token not found or already used")
+	}
+	if plan.DB != dbName || plan.Collection != collectionName {
+		return nil, fmt.Errorf("preview token does not belong to this collection")
+	}
+	delete(p.plans, token)
+	if time.Now().After(plan.ExpiresAt) {
+		return nil, fmt.Errorf("preview token expired")
+	}
+	return plan, nil
+}
+
+// PreviewBulk 预览批量更新/删除：返回匹配数、示例文档（更新时附带差异）和执行所需的令牌
+func (h *Handlers) PreviewBulk(c *gin.Context) {
+	dbName := c.Param("db")
+	collectionName := c.Param("collection")
+
+	var req struct {
+		Operation string        `bson:"operation"`
+		Filter    bson.D        `bson:"filter"`
+		Update    bson.RawValue `bson:"update"`
+		AllowAll  bool          `bson:"allowAll"`
+	}
+	if err := bindExtJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Filter == nil {
+		req.Filter = bson.D{}
+	}
+	if len(req.Filter) == 0 && !req.AllowAll {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Empty filter matches every document; set allowAll to confirm"})
+		return
+	}
+
+	var update interface{}
+	switch req.Operation {
+	case "update":
+		var err error
+		if update, err = parseUpdate(req.Update); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	case "delete":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "operation must be update or delete"})
+		return
+	}
+
+	preview, err := h.dbService.PreviewBulk(c.Request.Context(), dbName, collectionName, req.Filter, update, bulkPreviewSamples)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := gin.H{"preview": preview}
+	if limit := h.cfg.Bulk.MaxDocuments; limit > 0 && preview.Matched > limit {
+		response["error"] = fmt.Sprintf("Operation matches %d documents, exceeding the limit of %d", preview.Matched, limit)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	expiresAt := time.Now().Add(h.cfg.Bulk.PreviewTTL)
+	response["token"] = h.bulkPlans.add(&bulkPlan{
+		Operation:  req.Operation,
+		DB:         dbName,
+		Collection: collectionName,
+		Filter:     req.Filter,
+		Update:     update,
+		Matched:    preview.Matched,
+		ExpiresAt:  expiresAt,
+	})
+	response["expiresAt"] = expiresAt.Unix()
+	c.JSON(http.StatusOK, response)
+}
+
+// ExecuteBulk 使用预览令牌执行批量操作，以后台任务运行并返回任务信息
+func (h *Handlers) ExecuteBulk(c *gin.Context) {
+	var req struct {
+		Token string `json:"token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	plan, err := h.bulkPlans.take(req.Token, c.Param("db"), c.Param("collection"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filterJSON, _ := bson.MarshalExtJSON(plan.Filter, false, false)
+	params := map[string]interface{}{
+		"db":         plan.DB,
+		"collection": plan.Collection,
+		"filter":     string(filterJSON),
+		"matched":    plan.Matched,
+	}
+	description := fmt.Sprintf("%sMany %s.%s", plan.Operation, plan.DB, plan.Collection)
+
+	job := h.jobManager.Submit("bulk_"+plan.Operation, description, params, func(ctx context.Context, r *jobs.Reporter) (interface{}, error) {
+		// 预览之后匹配的文档数可能变化，执行时重新按上限检查
+		progress := func(done, total int64) {
+			if done == 0 && total != plan.Matched {
+				r.SetMessage(fmt.Sprintf("%d documents match now, %d at preview", total, plan.Matched))
+			}
+			r.SetTotal(total)
+			r.SetDone(done)
+		}
+		limit := h.cfg.Bulk.MaxDocuments
+		if plan.Operation == "delete" {
+			deleted, err := h.dbService.BulkDelete(ctx, plan.DB, plan.Collection, plan.Filter, limit, progress)
+			return gin.H{"deleted": deleted}, err
+		}
+		modified, err := h.dbService.BulkUpdate(ctx, plan.DB, plan.Collection, plan.Filter, plan.Update, limit, progress)
+		return gin.H{"modified": modified}, err
+	})
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// parseUpdate 解析更新语句：文档形式必须只包含更新运算符，数组形式为聚合管道
+func parseUpdate(raw bson.RawValue) (interface{}, error) {
+	switch raw.Type {
+	case bsontype.EmbeddedDocument:
+		var update bson.D
+		if err := raw.Unmarshal(&update); err != nil {
+			return nil, err
+		}
+		if len(update) == 0 {
+			return nil, fmt.Errorf("update must not be empty")
+		}
+		for _, e := range update {
+			if !strings.HasPrefix(e.Key, "$") {
+				return nil, fmt.Errorf("update document must only contain update operators, found %q", e.Key)
+			}
+		}
+		return update, nil
+	case bsontype.Array:
+		var pipeline mongo.Pipeline
+		if err := raw.Unmarshal(&pipeline); err != nil {
+			return nil, err
+		}
+		if len(pipeline) == 0 {
+			return nil, fmt.Errorf("update pipeline must not be empty")
+		}
+		return pipeline, nil
+	}
+	return nil, fmt.Errorf("update must be a document or a pipeline")
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"m-db-ui/internal/codegen"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// GenerateCode 根据查询条件（或聚合管道）和选项生成指定语言官方驱动的调用代码
+func (h *Handlers) GenerateCode(c *gin.Context) {
+	var req struct {
+		Language   string   `bson:"language"`
+		Query      bson.D   `bson:"query"`
+		Sort       bson.D   `bson:"sort"`
+		Projection bson.D   `bson:"projection"`
+		Pipeline   []bson.D `bson:"pipeline"`
+		Skip       int64    `bson:"skip"`
+		Limit      int64    `bson:"limit"`
+	}
+	if err := bindExtJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	code, err := codegen.Generate(req.Language, codegen.Query{
+		Database:   c.Param("db"),
+		Collection: c.Param("collection"),
+		Filter:     req.Query,
+		Sort:       req.Sort,
+		Projection: req.Projection,
+		Skip:       req.Skip,
+		Limit:      req.Limit,
+		Pipeline:   req.Pipeline,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"language": req.Language, "code": code})
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"m-db-ui/internal/database"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// commandRole 当前连接在命令控制台中的角色，只由服务端配置决定
+func (h *Handlers) commandRole() string {
+	return h.cfg.Commands.Role(h.connectionManager.GetCurrentID())
+}
+
+// ConsolePage 数据库命令控制台页面
+func (h *Handlers) ConsolePage(c *gin.Context) {
+	databases, err := h.dbService.GetDatabases(c.Request.Context())
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// 指定的数据库可能还不存在，仍然允许在其上执行命令
+	dbName := c.DefaultQuery("db", "admin")
+	found := false
+	for _, name := range databases {
+		found = found || name == dbName
+	}
+	if !found {
+		databases = append(databases, dbName)
+	}
+
+	c.HTML(http.StatusOK, "console.html", gin.H{
+		"title":     "命令控制台",
+		"enabled":   h.cfg.Commands.Enabled,
+		"role":      h.commandRole(),
+		"databases": databases,
+		"dbName":    dbName,
+	})
+}
+
+// GetCommands 列出服务端支持的命令，并标明当前连接的角色能否执行，用于控制台自动补全
+func (h *Handlers) GetCommands(c *gin.Context) {
+	if !h.cfg.Commands.Enabled {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Command console is disabled"})
+		return
+	}
+
+	commands, err := h.dbService.ListCommands(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	role := h.commandRole()
+	result := make([]gin.H, 0, len(commands))
+	for _, command := range commands {
+		result = append(result, gin.H{
+			"name":      command.Name,
+			"help":      command.Help,
+			"adminOnly": command.AdminOnly,
+			"allowed":   h.cfg.Commands.Allows(role, command.Name),
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"role": role, "commands": result})
+}
+
+// RunCommand 在数据库上执行命令，请求体为Extended JSON格式的命令文档，第一个字段为命令名
+func (h *Handlers) RunCommand(c *gin.Context) {
+	if !h.cfg.Commands.Enabled {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Command console is disabled"})
+		return
+	}
+	dbName := c.Param("db")
+
+	var command bson.D
+	if err := bindExtJSON(c, &command); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(command) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Command document must not be empty"})
+		return
+	}
+
+	name := command[0].Key
+	role := h.commandRole()
+	if !h.cfg.Commands.Allows(role, name) {
+		c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("Command %q is not allowed for role %q", name, role)})
+		return
+	}
+
+	started := time.Now()
+	result, err := h.dbService.RunCommand(c.Request.Context(), dbName, command)
+	duration := time.Since(started).Milliseconds()
+	if err != nil {
+		var cmdErr mongo.CommandError
+		if errors.As(err, &cmdErr) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":      cmdErr.Message,
+				"code":       cmdErr.Code,
+				"codeName":   cmdErr.Name,
+				"command":    name,
+				"durationMs": duration,
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	output, err := database.ExtJSON(result, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"command":    name,
+		"result":     output,
+		"durationMs": duration,
+	})
+}
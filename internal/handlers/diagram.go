@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"m-db-ui/internal/database"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetDiagram 生成数据库的实体关系图，format为 json（默认）、dot 或 mermaid，sample为每个集合采样的文档数
+func (h *Handlers) GetDiagram(c *gin.Context) {
+	dbName := c.Param("db")
+	sampleSize, _ := strconv.ParseInt(c.Query("sample"), 10, 64)
+
+	format := c.DefaultQuery("format", "json")
+	extensions := map[string]string{"json": ".json", "dot": ".dot", "mermaid": ".mmd"}
+	if extensions[format] == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json, dot or mermaid"})
+		return
+	}
+
+	mappings := make(map[string]map[string]database.ReferenceTarget)
+	for _, mapping := range h.referenceStore.List(h.connectionManager.GetCurrentID(), dbName, "") {
+		if mappings[mapping.Collection] == nil {
+			mappings[mapping.Collection] = make(map[string]database.ReferenceTarget)
+		}
+		mappings[mapping.Collection][mapping.Field] = database.ReferenceTarget{Database: mapping.TargetDatabase, Collection: mapping.TargetCollection}
+	}
+
+	diagram, err := h.dbService.RelationshipDiagram(c.Request.Context(), dbName, sampleSize, mappings)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if c.Query("download") != "" {
+		setAttachment(c, dbName+extensions[format])
+	}
+	switch format {
+	case "dot":
+		c.Data(http.StatusOK, "text/vnd.graphviz; charset=utf-8", []byte(diagram.DOT()))
+	case "mermaid":
+		c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(diagram.Mermaid()))
+	default:
+		c.IndentedJSON(http.StatusOK, diagram)
+	}
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"errors"
+	"m-db-ui/internal/database"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// loadForWrite 校验If-Match请求头并返回当前文档，作为写操作的原始版本。
+// 缺少If-Match时返回428，ETag不一致时返回412和当前版本；返回false表示已写入响应
+func (h *Handlers) loadForWrite(c *gin.Context, dbName, collectionName, id string) (bson.D, bool) {
+	ifMatch := c.GetHeader("If-Match")
+	if ifMatch == "" {
+		c.JSON(http.StatusPreconditionRequired, gin.H{"error": "If-Match header is required; fetch the document first to obtain its ETag"})
+		return nil, false
+	}
+
+	current, err := h.dbService.GetDocument(c.Request.Context(), dbName, collectionName, id)
+	if err != nil {
+		documentError(c, err)
+		return nil, false
+	}
+	etag, err := database.DocumentETag(current)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if !etagMatches(ifMatch, etag) {
+		preconditionFailed(c, current, nil)
+		return nil, false
+	}
+	return current, true
+}
+
+// etagMatches 按强比较判断If-Match是否包含指定ETag，"*" 匹配任意版本
+func etagMatches(ifMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// writeError 处理带原始版本的写操作错误：文档在校验之后被修改时重新读取并返回412
+func (h *Handlers) writeError(c *gin.Context, dbName, collectionName, id string, err error, submitted bson.D) {
+	if !errors.Is(err, database.ErrDocumentModified) {
+		documentError(c, err)
+		return
+	}
+	current, err := h.dbService.GetDocument(c.Request.Context(), dbName, collectionName, id)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Document has been deleted"})
+			return
+		}
+		documentError(c, err)
+		return
+	}
+	preconditionFailed(c, current, submitted)
+}
+
+// preconditionFailed 返回412和当前版本的文档及ETag；submitted不为空时附带提交内容相对当前版本的差异，供前端合并
+func preconditionFailed(c *gin.Context, current, submitted bson.D) {
+	etag, err := database.DocumentETag(current)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	document, err := database.ExtJSON(current, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := gin.H{
+		"error":    "Document has been modified",
+		"etag":     etag,
+		"document": document,
+	}
+	if submitted != nil {
+		changes := []database.FieldChange{}
+		for _, change := range database.DiffDocuments(current, submitted) {
+			// 替换时请求体可以省略_id
+			if change.Path != "_id" {
+				changes = append(changes, change)
+			}
+		}
+		response["changes"] = changes
+	}
+	c.Header("ETag", etag)
+	c.JSON(http.StatusPreconditionFailed, response)
+}
+
+// respondWritten 写入成功后返回新版本的ETag
+func (h *Handlers) respondWritten(c *gin.Context, dbName, collectionName, id, message string) {
+	response := gin.H{"message": message}
+	if document, err := h.dbService.GetDocument(c.Request.Context(), dbName, collectionName, id); err == nil {
+		if etag, err := database.DocumentETag(document); err == nil {
+			c.Header("ETag", etag)
+			response["etag"] = etag
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -2,31 +2,75 @@ package handlers
 
 import (
 	"context"
+	"fmt"
 	"m-db-ui/internal/config"
 	"m-db-ui/internal/database"
+	"m-db-ui/internal/jobs"
+	"m-db-ui/internal/queries"
+	"m-db-ui/internal/references"
 	"net/http"
-	"net/url"
 	"strconv"
-	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
 )
 
 type Handlers struct {
+	cfg               *config.Config
 	dbService         *database.Service
 	connectionManager *config.ConnectionManager
+	jobManager        *jobs.Manager
+	queryStore        *queries.Store
+	referenceStore    *references.Store
+	bulkPlans         *bulkPlans
 }
 
-func New(dbService *database.Service, connectionManager *config.ConnectionManager) *Handlers {
+func New(cfg *config.Config, dbService *database.Service, connectionManager *config.ConnectionManager, jobManager *jobs.Manager, queryStore *queries.Store, referenceStore *references.Store) *Handlers {
 	return &Handlers{
+		cfg:               cfg,
 		dbService:         dbService,
 		connectionManager: connectionManager,
+		jobManager:        jobManager,
+		queryStore:        queryStore,
+		referenceStore:    referenceStore,
+		bulkPlans:         newBulkPlans(),
 	}
 }
 
+// pageParams 解析分页参数，未指定时使用第1页和默认每页数量，每页数量受配置的上限约束
+func (h *Handlers) pageParams(pageStr, limitStr string) (int64, int64, error) {
+	page := int64(1)
+	if pageStr != "" {
+		var err error
+		if page, err = strconv.ParseInt(pageStr, 10, 64); err != nil {
+			return 0, 0, fmt.Errorf("invalid page %q", pageStr)
+		}
+		if page < 1 {
+			page = 1
+		}
+	}
+	var limit int64
+	if limitStr != "" {
+		var err error
+		if limit, err = strconv.ParseInt(limitStr, 10, 64); err != nil {
+			return 0, 0, fmt.Errorf("invalid limit %q", limitStr)
+		}
+	}
+	return page, h.clampLimit(limit), nil
+}
+
+// clampLimit 每页数量超出范围时使用默认值
+func (h *Handlers) clampLimit(limit int64) int64 {
+	if limit < 1 || limit > h.cfg.Pagination.MaxPageSize {
+		return h.cfg.Pagination.DefaultPageSize
+	}
+	return limit
+}
+
 // Index 首页
 func (h *Handlers) Index(c *gin.Context) {
-	databases, err := h.dbService.GetDatabases()
+	databases, err := h.dbService.GetDatabases(c.Request.Context())
 	if err != nil {
 		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
 			"error": err.Error(),
@@ -59,7 +103,7 @@ func (h *Handlers) ConnectionsPage(c *gin.Context) {
 func (h *Handlers) DatabasePage(c *gin.Context) {
 	dbName := c.Param("db")
 
-	dbInfo, err := h.dbService.GetDatabase(dbName)
+	dbInfo, err := h.dbService.GetDatabase(c.Request.Context(), dbName)
 	if err != nil {
 		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
 			"error": err.Error(),
@@ -73,24 +117,28 @@ func (h *Handlers) DatabasePage(c *gin.Context) {
 	})
 }
 
-// CollectionPage 集合页面
+// CollectionPage 集合页面，URL中的 filter、sort、projection、pipeline 参数会在服务端应用，doc 参数指定打开后直接编辑的文档
 func (h *Handlers) CollectionPage(c *gin.Context) {
 	dbName := c.Param("db")
 	collectionName := c.Param("collection")
 
-	pageStr := c.DefaultQuery("page", "1")
-	page, err := strconv.ParseInt(pageStr, 10, 64)
-	if err != nil || page < 1 {
-		page = 1
+	page, limit, err := h.pageParams(c.Query("page"), c.Query("limit"))
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{
+			"error": err.Error(),
+		})
+		return
 	}
 
-	limitStr := c.DefaultQuery("limit", "20")
-	limit, err := strconv.ParseInt(limitStr, 10, 64)
-	if err != nil || limit < 1 || limit > 100 {
-		limit = 20
+	state, err := parseQueryState(c, limit)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{
+			"error": err.Error(),
+		})
+		return
 	}
 
-	documents, err := h.dbService.GetDocuments(dbName, collectionName, page, limit)
+	documents, err := h.dbService.QueryDocuments(c.Request.Context(), dbName, collectionName, state.Query, page, limit, 0)
 	if err != nil {
 		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
 			"error": err.Error(),
@@ -126,11 +174,13 @@ func (h *Handlers) CollectionPage(c *gin.Context) {
 		endRecord = documents.Total
 	}
 
+	// gridData 供表格视图使用，canonical Extended JSON保留准确的类型
 	c.HTML(http.StatusOK, "base.html", gin.H{
 		"title":       collectionName + " - 集合管理",
 		"dbName":      dbName,
 		"collection":  collectionName,
 		"documents":   documents.Documents,
+		"gridData":    database.CanonicalExtJSONValue(documents.Documents),
 		"total":       documents.Total,
 		"page":        documents.Page,
 		"limit":       documents.Limit,
@@ -138,12 +188,13 @@ func (h *Handlers) CollectionPage(c *gin.Context) {
 		"pageNumbers": pageNumbers,
 		"start":       startRecord,
 		"end":         endRecord,
+		"queryState":  state,
 	})
 }
 
 // GetDatabases 获取所有数据库
 func (h *Handlers) GetDatabases(c *gin.Context) {
-	databases, err := h.dbService.GetDatabases()
+	databases, err := h.dbService.GetDatabases(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -155,7 +206,7 @@ func (h *Handlers) GetDatabases(c *gin.Context) {
 func (h *Handlers) GetDatabase(c *gin.Context) {
 	dbName := c.Param("name")
 
-	dbInfo, err := h.dbService.GetDatabase(dbName)
+	dbInfo, err := h.dbService.GetDatabase(c.Request.Context(), dbName)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -167,7 +218,7 @@ func (h *Handlers) GetDatabase(c *gin.Context) {
 func (h *Handlers) DeleteDatabase(c *gin.Context) {
 	dbName := c.Param("name")
 
-	err := h.dbService.DeleteDatabase(dbName)
+	err := h.dbService.DeleteDatabase(c.Request.Context(), dbName)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -186,7 +237,7 @@ func (h *Handlers) CreateDatabase(c *gin.Context) {
 		return
 	}
 
-	err := h.dbService.CreateDatabase(req.Name)
+	err := h.dbService.CreateDatabase(c.Request.Context(), req.Name)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -198,7 +249,7 @@ func (h *Handlers) CreateDatabase(c *gin.Context) {
 func (h *Handlers) GetCollections(c *gin.Context) {
 	dbName := c.Param("db")
 
-	collections, err := h.dbService.GetCollections(dbName)
+	collections, err := h.dbService.GetCollections(c.Request.Context(), dbName)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -219,7 +270,7 @@ func (h *Handlers) CreateCollection(c *gin.Context) {
 		return
 	}
 
-	err := h.dbService.CreateCollection(dbName, req.Name)
+	err := h.dbService.CreateCollection(c.Request.Context(), dbName, req.Name)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -227,12 +278,27 @@ func (h *Handlers) CreateCollection(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Collection created successfully"})
 }
 
-// DeleteCollection 删除集合
+// DeleteCollection 删除集合，启用回收站时以后台任务把集合移入回收站并返回任务
 func (h *Handlers) DeleteCollection(c *gin.Context) {
 	dbName := c.Param("db")
 	collectionName := c.Param("collection")
 
-	err := h.dbService.DeleteCollection(dbName, collectionName)
+	if h.useTrash(c, dbName) {
+		trash, deletedBy := h.trash(), h.requestUser(c)
+		params := map[string]interface{}{"db": dbName, "collection": collectionName}
+		description := fmt.Sprintf("move %s.%s to recycle bin", dbName, collectionName)
+		job := h.jobManager.Submit("trash-collection", description, params, func(ctx context.Context, r *jobs.Reporter) (interface{}, error) {
+			entry, err := trash.TrashCollection(ctx, dbName, collectionName, deletedBy)
+			if err != nil {
+				return nil, err
+			}
+			return gin.H{"trashId": entry.ID.Hex(), "documentCount": entry.DocumentCount}, nil
+		})
+		c.JSON(http.StatusAccepted, job)
+		return
+	}
+
+	err := h.dbService.DeleteCollection(c.Request.Context(), dbName, collectionName)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -245,19 +311,15 @@ func (h *Handlers) GetDocuments(c *gin.Context) {
 	dbName := c.Param("db")
 	collectionName := c.Param("collection")
 
-	pageStr := c.DefaultQuery("page", "1")
-	page, err := strconv.ParseInt(pageStr, 10, 64)
-	if err != nil || page < 1 {
-		page = 1
+	page, limit, err := h.pageParams(c.Query("page"), c.Query("limit"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	limitStr := c.DefaultQuery("limit", "20")
-	limit, err := strconv.ParseInt(limitStr, 10, 64)
-	if err != nil || limit < 1 || limit > 100 {
-		limit = 20
-	}
+	maxTimeMS, _ := strconv.ParseInt(c.Query("maxTimeMS"), 10, 64)
 
-	documents, err := h.dbService.GetDocuments(dbName, collectionName, page, limit)
+	documents, err := h.dbService.GetDocuments(c.Request.Context(), dbName, collectionName, page, limit, time.Duration(maxTimeMS)*time.Millisecond)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -265,32 +327,38 @@ func (h *Handlers) GetDocuments(c *gin.Context) {
 	c.JSON(http.StatusOK, documents)
 }
 
-// GetDocument 获取单个文档
+// GetDocument 获取单个文档，以relaxed Extended JSON返回以便编辑后原样提交
 func (h *Handlers) GetDocument(c *gin.Context) {
 	dbName := c.Param("db")
 	collectionName := c.Param("collection")
-	id := c.Param("id")
 
-	// URL解码
-	decodedID, err := url.QueryUnescape(id)
+	id, err := documentID(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// 移除ObjectID()包装，如果存在
-	if len(decodedID) > 9 && decodedID[:9] == "ObjectID(" && decodedID[len(decodedID)-1] == ')' {
-		decodedID = decodedID[9 : len(decodedID)-1]
-		// 移除可能的引号
-		decodedID = strings.Trim(decodedID, `"`)
+	document, err := h.dbService.GetDocument(c.Request.Context(), dbName, collectionName, id)
+	if err != nil {
+		documentError(c, err)
+		return
+	}
+	etag, err := database.DocumentETag(document)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	// canonical参数不为空时返回canonical Extended JSON，保留数值类型
+	data, err := database.ExtJSON(document, false)
+	if c.Query("canonical") != "" {
+		data, err = database.ExtJSON(document, true)
 	}
-
-	document, err := h.dbService.GetDocument(dbName, collectionName, decodedID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, document)
+	c.Header("ETag", etag)
+	c.JSON(http.StatusOK, data)
 }
 
 // CreateDocument 创建文档
@@ -298,13 +366,14 @@ func (h *Handlers) CreateDocument(c *gin.Context) {
 	dbName := c.Param("db")
 	collectionName := c.Param("collection")
 
-	var document map[string]interface{}
-	if err := c.ShouldBindJSON(&document); err != nil {
+	// 请求体为Extended JSON，保留ObjectId、日期、Int64等类型和字段顺序
+	var document bson.D
+	if err := bindExtJSON(c, &document); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	id, err := h.dbService.CreateDocument(dbName, collectionName, document)
+	id, err := h.dbService.CreateDocument(c.Request.Context(), dbName, collectionName, document)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -312,52 +381,88 @@ func (h *Handlers) CreateDocument(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"id": id})
 }
 
-// UpdateDocument 更新文档
-func (h *Handlers) UpdateDocument(c *gin.Context) {
+// ReplaceDocument 整体替换文档，请求体中省略的字段会被删除；需要If-Match与当前版本一致
+func (h *Handlers) ReplaceDocument(c *gin.Context) {
 	dbName := c.Param("db")
 	collectionName := c.Param("collection")
-	id := c.Param("id")
 
-	var document map[string]interface{}
-	if err := c.ShouldBindJSON(&document); err != nil {
+	id, err := documentID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var document bson.D
+	if err := bindExtJSON(c, &document); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	err := h.dbService.UpdateDocument(dbName, collectionName, id, document)
+	original, ok := h.loadForWrite(c, dbName, collectionName, id)
+	if !ok {
+		return
+	}
+
+	err = h.writeWithRevision(c, dbName, collectionName, original, "replace", func() error {
+		return h.dbService.ReplaceDocument(c.Request.Context(), dbName, collectionName, id, document, original)
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		h.writeError(c, dbName, collectionName, id, err, document)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "Document updated successfully"})
+	h.respondWritten(c, dbName, collectionName, id, "Document replaced successfully")
 }
 
-// DeleteDocument 删除文档
+// DeleteDocument 删除文档，需要If-Match与当前版本一致；启用回收站时文档会先复制到回收站
 func (h *Handlers) DeleteDocument(c *gin.Context) {
 	dbName := c.Param("db")
 	collectionName := c.Param("collection")
-	id := c.Param("id")
 
-	err := h.dbService.DeleteDocument(dbName, collectionName, id)
+	id, err := documentID(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	original, ok := h.loadForWrite(c, dbName, collectionName, id)
+	if !ok {
+		return
+	}
+
+	if h.useTrash(c, dbName) {
+		entry, err := h.trash().TrashDocument(c.Request.Context(), dbName, collectionName, id, original, h.requestUser(c))
+		if err != nil {
+			h.writeError(c, dbName, collectionName, id, err, nil)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Document moved to recycle bin", "trashId": entry.ID.Hex()})
+		return
+	}
+
+	err = h.dbService.DeleteDocument(c.Request.Context(), dbName, collectionName, id, original)
+	if err != nil {
+		h.writeError(c, dbName, collectionName, id, err, nil)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Document deleted successfully"})
 }
 
-// QueryDocuments 查询文档
+// QueryDocuments 查询文档，支持排序、投影和聚合管道，并记录到用户的查询历史
 func (h *Handlers) QueryDocuments(c *gin.Context) {
 	dbName := c.Param("db")
 	collectionName := c.Param("collection")
 
 	var req struct {
-		Query map[string]interface{} `json:"query"`
-		Page  int64                  `json:"page"`
-		Limit int64                  `json:"limit"`
+		Query      bson.D   `bson:"query"`
+		Sort       bson.D   `bson:"sort"`
+		Projection bson.D   `bson:"projection"`
+		Pipeline   []bson.D `bson:"pipeline"`
+		Page       int64    `bson:"page"`
+		Limit      int64    `bson:"limit"`
+		MaxTimeMS  int64    `bson:"maxTimeMS"`
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := bindExtJSON(c, &req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -365,11 +470,17 @@ func (h *Handlers) QueryDocuments(c *gin.Context) {
 	if req.Page < 1 {
 		req.Page = 1
 	}
-	if req.Limit < 1 || req.Limit > 100 {
-		req.Limit = 20
-	}
+	req.Limit = h.clampLimit(req.Limit)
 
-	documents, err := h.dbService.QueryDocuments(dbName, collectionName, req.Query, req.Page, req.Limit)
+	query := database.Query{
+		Filter:     req.Query,
+		Sort:       req.Sort,
+		Projection: req.Projection,
+		Pipeline:   req.Pipeline,
+	}
+	started := time.Now()
+	documents, err := h.dbService.QueryDocuments(c.Request.Context(), dbName, collectionName, query, req.Page, req.Limit, time.Duration(req.MaxTimeMS)*time.Millisecond)
+	h.recordHistory(c, dbName, collectionName, query, time.Since(started), documents, err)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -379,7 +490,7 @@ func (h *Handlers) QueryDocuments(c *gin.Context) {
 
 // GetStats 获取统计信息
 func (h *Handlers) GetStats(c *gin.Context) {
-	stats, err := h.dbService.GetStats()
+	stats, err := h.dbService.GetStats(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -481,7 +592,7 @@ func (h *Handlers) TestConnection(c *gin.Context) {
 	}
 
 	// 测试连接
-	client, err := database.Connect(config.GetURI())
+	client, err := database.Connect(config.GetURI(), h.cfg.MongoDB.ConnectOptions())
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to connect: " + err.Error()})
 		return
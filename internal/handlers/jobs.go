@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 后台任务相关处理器
+
+// GetJobs 获取所有后台任务
+func (h *Handlers) GetJobs(c *gin.Context) {
+	c.JSON(http.StatusOK, h.jobManager.List())
+}
+
+// GetJob 获取任务详情（包含进度和预计剩余时间）
+func (h *Handlers) GetJob(c *gin.Context) {
+	job, err := h.jobManager.Get(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// StreamJob 通过SSE推送任务进度，任务结束后关闭连接
+func (h *Handlers) StreamJob(c *gin.Context) {
+	events, unsubscribe, err := h.jobManager.Subscribe(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	defer unsubscribe()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			c.SSEvent(event.Type, event)
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
+
+// DeleteJob 取消运行中的任务；已结束的任务则删除记录
+func (h *Handlers) DeleteJob(c *gin.Context) {
+	id := c.Param("id")
+
+	job, err := h.jobManager.Get(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !job.Status.Finished() {
+		if err := h.jobManager.Cancel(id); err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusAccepted, gin.H{"message": "Job cancellation requested"})
+		return
+	}
+
+	if err := h.jobManager.Remove(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Job deleted successfully"})
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"m-db-ui/internal/database"
+	"mime"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+// PATCH请求体的媒体类型
+const (
+	mergePatchContentType = "application/merge-patch+json"
+	jsonPatchContentType  = "application/json-patch+json"
+)
+
+// PatchDocument 部分更新文档，按Content-Type区分三种格式：
+//   - application/json: 更新运算符文档，或 {"update": ..., "arrayFilters": [...]}
+//   - application/merge-patch+json: RFC 7386 JSON Merge Patch
+//   - application/json-patch+json: RFC 6902 JSON Patch
+//
+// 与PUT相同，需要If-Match与当前版本一致
+func (h *Handlers) PatchDocument(c *gin.Context) {
+	dbName := c.Param("db")
+	collectionName := c.Param("collection")
+
+	id, err := documentID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	contentType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	switch contentType {
+	case mergePatchContentType, jsonPatchContentType:
+		var patch func(bson.D) (bson.D, error)
+		if contentType == mergePatchContentType {
+			var mergePatch bson.D
+			if err := bindExtJSON(c, &mergePatch); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			patch = func(doc bson.D) (bson.D, error) {
+				return database.MergePatch(doc, mergePatch), nil
+			}
+		} else {
+			operations, err := bindJSONPatch(c)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			patch = func(doc bson.D) (bson.D, error) {
+				return database.ApplyJSONPatch(doc, operations)
+			}
+		}
+
+		original, ok := h.loadForWrite(c, dbName, collectionName, id)
+		if !ok {
+			return
+		}
+		patched, err := patch(original)
+		if err != nil {
+			if errors.Is(err, database.ErrPatchTestFailed) {
+				documentError(c, err)
+				return
+			}
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		err = h.writeWithRevision(c, dbName, collectionName, original, "patch", func() error {
+			return h.dbService.ReplaceDocument(c.Request.Context(), dbName, collectionName, id, patched, original)
+		})
+		if err != nil {
+			h.writeError(c, dbName, collectionName, id, err, patched)
+			return
+		}
+
+	case "", "application/json":
+		update, arrayFilters, err := bindUpdate(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		original, ok := h.loadForWrite(c, dbName, collectionName, id)
+		if !ok {
+			return
+		}
+		err = h.writeWithRevision(c, dbName, collectionName, original, "update", func() error {
+			return h.dbService.UpdateDocument(c.Request.Context(), dbName, collectionName, id, update, arrayFilters, original)
+		})
+		if err != nil {
+			h.writeError(c, dbName, collectionName, id, err, nil)
+			return
+		}
+
+	default:
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": fmt.Sprintf("Unsupported patch format %q", contentType)})
+		return
+	}
+
+	h.respondWritten(c, dbName, collectionName, id, "Document updated successfully")
+}
+
+// bindUpdate 解析更新请求：整个请求体为更新运算符文档，或以update、arrayFilters字段分别给出
+func bindUpdate(c *gin.Context) (interface{}, []interface{}, error) {
+	var body bson.Raw
+	if err := bindExtJSON(c, &body); err != nil {
+		return nil, nil, err
+	}
+
+	updateValue, err := body.LookupErr("update")
+	if err != nil {
+		update, err := parseUpdate(bson.RawValue{Type: bsontype.EmbeddedDocument, Value: body})
+		return update, nil, err
+	}
+
+	update, err := parseUpdate(updateValue)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var arrayFilters []interface{}
+	if value, err := body.LookupErr("arrayFilters"); err == nil {
+		var filters bson.A
+		if err := value.Unmarshal(&filters); err != nil {
+			return nil, nil, fmt.Errorf("arrayFilters must be an array of documents")
+		}
+		arrayFilters = filters
+	}
+	return update, arrayFilters, nil
+}
+
+// bindJSONPatch 解析JSON Patch操作数组；Extended JSON要求顶层为文档，因此包装后再解析
+func bindJSONPatch(c *gin.Context) ([]database.JSONPatchOperation, error) {
+	body, err := c.GetRawData()
+	if err != nil {
+		return nil, err
+	}
+
+	var wrapper struct {
+		Operations []database.JSONPatchOperation `bson:"operations"`
+	}
+	wrapped := append(append([]byte(`{"operations":`), body...), '}')
+	if err := bson.UnmarshalExtJSON(wrapped, false, &wrapper); err != nil {
+		return nil, fmt.Errorf("JSON Patch must be an array of operations: %v", err)
+	}
+	if len(wrapper.Operations) == 0 {
+		return nil, fmt.Errorf("JSON Patch must not be empty")
+	}
+	return wrapper.Operations, nil
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"m-db-ui/internal/database"
+	"m-db-ui/internal/queries"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// queryHistoryLimit 查询历史默认返回的条数
+const queryHistoryLimit = 50
+
+// recordHistory 记录一次查询到当前用户的历史，记录失败只写入请求日志，不影响查询结果
+func (h *Handlers) recordHistory(c *gin.Context, dbName, collectionName string, query database.Query, duration time.Duration, result *database.DocumentsResponse, queryErr error) {
+	entry := &queries.HistoryEntry{
+		User:       h.requestUser(c),
+		Connection: h.connectionManager.GetCurrentID(),
+		Database:   dbName,
+		Collection: collectionName,
+		Query:      historyQuery(query),
+		DurationMS: duration.Milliseconds(),
+	}
+	if queryErr != nil {
+		entry.Error = queryErr.Error()
+	} else {
+		entry.ResultCount = result.Total
+	}
+
+	h.queryStore.AddHistory(entry)
+}
+
+// historyQuery 将查询条件转换为relaxed Extended JSON保存，未指定的部分省略
+func historyQuery(query database.Query) queries.Query {
+	var result queries.Query
+	if query.Filter != nil {
+		result.Filter = database.ExtJSONValue(query.Filter)
+	}
+	if len(query.Sort) > 0 {
+		result.Sort = database.ExtJSONValue(query.Sort)
+	}
+	if len(query.Projection) > 0 {
+		result.Projection = database.ExtJSONValue(query.Projection)
+	}
+	if len(query.Pipeline) > 0 {
+		result.Pipeline = database.ExtJSONValue(query.Pipeline)
+	}
+	return result
+}
+
+// GetQueryHistory 获取当前用户的查询历史，可按数据库和集合过滤
+func (h *Handlers) GetQueryHistory(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(queryHistoryLimit)))
+	if err != nil || limit < 1 {
+		limit = queryHistoryLimit
+	}
+
+	history := h.queryStore.History(h.requestUser(c), c.Query("db"), c.Query("collection"), limit)
+	c.JSON(http.StatusOK, gin.H{"history": history})
+}
+
+// ClearQueryHistory 清空当前用户的查询历史
+func (h *Handlers) ClearQueryHistory(c *gin.Context) {
+	if err := h.queryStore.ClearHistory(h.requestUser(c)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Query history cleared successfully"})
+}
+
+// GetSavedQueries 获取当前用户可见的保存的查询：自己保存的和团队共享的
+func (h *Handlers) GetSavedQueries(c *gin.Context) {
+	saved := h.queryStore.ListSaved(h.requestUser(c), c.Query("db"), c.Query("collection"))
+	c.JSON(http.StatusOK, gin.H{"queries": saved, "user": h.requestUser(c)})
+}
+
+// CreateSavedQuery 保存查询
+func (h *Handlers) CreateSavedQuery(c *gin.Context) {
+	query, err := bindSavedQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if query.Connection == "" {
+		query.Connection = h.connectionManager.GetCurrentID()
+	}
+
+	if err := h.queryStore.AddSaved(h.requestUser(c), query); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Query saved successfully", "query": query})
+}
+
+// UpdateSavedQuery 更新保存的查询，只有创建者可以修改
+func (h *Handlers) UpdateSavedQuery(c *gin.Context) {
+	query, err := bindSavedQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if query.Connection == "" {
+		query.Connection = h.connectionManager.GetCurrentID()
+	}
+
+	if err := h.queryStore.UpdateSaved(h.requestUser(c), c.Param("id"), query); err != nil {
+		savedQueryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Query updated successfully", "query": query})
+}
+
+// DeleteSavedQuery 删除保存的查询，只有创建者可以删除
+func (h *Handlers) DeleteSavedQuery(c *gin.Context) {
+	if err := h.queryStore.DeleteSaved(h.requestUser(c), c.Param("id")); err != nil {
+		savedQueryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Query deleted successfully"})
+}
+
+// bindSavedQuery 解析保存的查询，并校验各查询部分是合法的Extended JSON
+func bindSavedQuery(c *gin.Context) (*queries.SavedQuery, error) {
+	var req struct {
+		Name        string        `json:"name" binding:"required"`
+		Description string        `json:"description"`
+		Shared      bool          `json:"shared"`
+		Connection  string        `json:"connection"`
+		Database    string        `json:"database" binding:"required"`
+		Collection  string        `json:"collection" binding:"required"`
+		Query       queries.Query `json:"query"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, errors.New("Query name is required")
+	}
+
+	documents := map[string]json.RawMessage{
+		"filter":     req.Query.Filter,
+		"sort":       req.Query.Sort,
+		"projection": req.Query.Projection,
+	}
+	for field, value := range documents {
+		if len(value) == 0 || string(value) == "null" {
+			continue
+		}
+		var doc bson.D
+		if err := bson.UnmarshalExtJSON(value, false, &doc); err != nil {
+			return nil, fmt.Errorf("Invalid %s: %v", field, err)
+		}
+	}
+	if len(req.Query.Pipeline) > 0 && string(req.Query.Pipeline) != "null" {
+		var wrapper struct {
+			Pipeline []bson.D `bson:"pipeline"`
+		}
+		wrapped := append(append([]byte(`{"pipeline":`), req.Query.Pipeline...), '}')
+		if err := bson.UnmarshalExtJSON(wrapped, false, &wrapper); err != nil {
+			return nil, fmt.Errorf("Invalid pipeline: %v", err)
+		}
+	}
+
+	return &queries.SavedQuery{
+		Name:        name,
+		Description: req.Description,
+		Shared:      req.Shared,
+		Connection:  req.Connection,
+		Database:    req.Database,
+		Collection:  req.Collection,
+		Query:       req.Query,
+	}, nil
+}
+
+// savedQueryError 将保存的查询的错误转换为对应的HTTP状态码
+func savedQueryError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, queries.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, queries.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
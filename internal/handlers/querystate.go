@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"fmt"
+	"html/template"
+	"m-db-ui/internal/database"
+	"net/url"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// 集合页面URL中保存查询状态的参数，值为Extended JSON文本
+var queryStateParams = []string{"filter", "sort", "projection", "pipeline"}
+
+// queryState 从集合页面URL中解析出的查询状态，用于分享指向查询结果或文档的链接
+type queryState struct {
+	Query database.Query
+	// Text 各参数规范化后的relaxed Extended JSON，用于回填查询框
+	Text map[string]string
+	// PageQuery 翻页链接需要保留的参数（不含page）
+	PageQuery template.URL
+	// Document 打开后直接在编辑器中显示的文档ID
+	Document string
+}
+
+// Filtered 是否指定了任何查询条件
+func (q *queryState) Filtered() bool {
+	return len(q.Text) > 0
+}
+
+// parseQueryState 解析 filter、sort、projection、pipeline、limit、doc 参数，任一参数不是合法的Extended JSON时返回错误
+func parseQueryState(c *gin.Context, limit int64) (*queryState, error) {
+	state := &queryState{Text: map[string]string{}, Document: c.Query("doc")}
+	pageQuery := url.Values{}
+
+	for _, name := range queryStateParams {
+		text := c.Query(name)
+		if text == "" {
+			continue
+		}
+
+		var value interface{}
+		var err error
+		if name == "pipeline" {
+			err = decodeExtJSONParam(text, &state.Query.Pipeline)
+			value = state.Query.Pipeline
+		} else {
+			var doc bson.D
+			err = decodeExtJSONParam(text, &doc)
+			value = doc
+			switch name {
+			case "filter":
+				state.Query.Filter = doc
+			case "sort":
+				state.Query.Sort = doc
+			case "projection":
+				state.Query.Projection = doc
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Invalid %s parameter: %v", name, err)
+		}
+
+		state.Text[name] = string(database.ExtJSONValue(value))
+		pageQuery.Set(name, state.Text[name])
+	}
+
+	pageQuery.Set("limit", strconv.FormatInt(limit, 10))
+	state.PageQuery = template.URL(pageQuery.Encode())
+	return state, nil
+}
+
+// decodeExtJSONParam 解析URL参数中的Extended JSON；顶层必须为文档，因此包装后再解析，以便同时支持数组
+func decodeExtJSONParam(text string, v interface{}) error {
+	var wrapper bson.Raw
+	if err := bson.UnmarshalExtJSON([]byte(`{"v":`+text+`}`), false, &wrapper); err != nil {
+		return err
+	}
+	return wrapper.Lookup("v").Unmarshal(v)
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"m-db-ui/internal/database"
+	"m-db-ui/internal/references"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// referrerLimit 查找引用方时每组默认返回的文档数
+	referrerLimit = 20
+	// maxResolveDocuments 一次识别引用的文档数上限
+	maxResolveDocuments = 500
+)
+
+// ResolveReferences 识别请求体中文档（Extended JSON，{"documents": [...]}）里的引用，返回每个引用的路径和被引用的集合
+func (h *Handlers) ResolveReferences(c *gin.Context) {
+	dbName := c.Param("db")
+	collectionName := c.Param("collection")
+
+	var req struct {
+		Documents []bson.D `bson:"documents"`
+	}
+	if err := bindExtJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(req.Documents) > maxResolveDocuments {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("at most %d documents can be resolved at once", maxResolveDocuments)})
+		return
+	}
+
+	mappings := make(map[string]database.ReferenceTarget)
+	for _, mapping := range h.referenceStore.List(h.connectionManager.GetCurrentID(), dbName, collectionName) {
+		mappings[mapping.Field] = database.ReferenceTarget{Database: mapping.TargetDatabase, Collection: mapping.TargetCollection}
+	}
+
+	refs, err := h.dbService.ResolveReferences(c.Request.Context(), dbName, req.Documents, mappings)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"references": refs})
+}
+
+// GetReferrers 查找引用了该文档的文档，按集合和字段分组
+func (h *Handlers) GetReferrers(c *gin.Context) {
+	dbName := c.Param("db")
+	collectionName := c.Param("collection")
+
+	id, err := documentID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", strconv.Itoa(referrerLimit)), 10, 64)
+	if err != nil || limit < 1 {
+		limit = referrerLimit
+	}
+
+	var fields []database.ReferenceField
+	for _, mapping := range h.referenceStore.Targeting(h.connectionManager.GetCurrentID(), dbName, collectionName) {
+		fields = append(fields, database.ReferenceField{Database: mapping.Database, Collection: mapping.Collection, Field: mapping.Field})
+	}
+
+	result, err := h.dbService.FindReferrers(c.Request.Context(), dbName, collectionName, id, fields, limit)
+	if err != nil {
+		if errors.Is(err, primitive.ErrInvalidHex) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// GetReferenceMappings 获取当前连接的引用映射，可按数据库和集合过滤
+func (h *Handlers) GetReferenceMappings(c *gin.Context) {
+	mappings := h.referenceStore.List(h.connectionManager.GetCurrentID(), c.Query("db"), c.Query("collection"))
+	c.JSON(http.StatusOK, gin.H{"mappings": mappings})
+}
+
+// CreateReferenceMapping 添加引用映射，同一字段已有的映射会被替换
+func (h *Handlers) CreateReferenceMapping(c *gin.Context) {
+	var req struct {
+		Database         string `json:"database" binding:"required"`
+		Collection       string `json:"collection" binding:"required"`
+		Field            string `json:"field" binding:"required"`
+		TargetDatabase   string `json:"targetDatabase"`
+		TargetCollection string `json:"targetCollection" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	field := strings.TrimSpace(req.Field)
+	if field == "" || strings.HasPrefix(field, ".") || strings.HasSuffix(field, ".") || strings.Contains(field, "..") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid field path"})
+		return
+	}
+	if req.TargetDatabase == "" {
+		req.TargetDatabase = req.Database
+	}
+
+	mapping := &references.Mapping{
+		Connection:       h.connectionManager.GetCurrentID(),
+		Database:         req.Database,
+		Collection:       req.Collection,
+		Field:            field,
+		TargetDatabase:   req.TargetDatabase,
+		TargetCollection: req.TargetCollection,
+	}
+	if err := h.referenceStore.Add(h.requestUser(c), mapping); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Reference mapping saved successfully", "mapping": mapping})
+}
+
+// DeleteReferenceMapping 删除引用映射
+func (h *Handlers) DeleteReferenceMapping(c *gin.Context) {
+	if err := h.referenceStore.Delete(c.Param("id")); err != nil {
+		if errors.Is(err, references.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Reference mapping deleted successfully"})
+}
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"m-db-ui/internal/database"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// currentRevision 表示文档当前版本的版本号参数
+const currentRevision = "current"
+
+var errInvalidRevision = errors.New("revision must be a number or current")
+
+// trackRevisions 是否为集合记录历史版本；版本集合本身和回收站中的数据不记录
+func (h *Handlers) trackRevisions(dbName, collectionName string) bool {
+	if strings.HasSuffix(collectionName, database.RevisionSuffix) {
+		return false
+	}
+	if h.cfg.Trash.Enabled && dbName == h.cfg.Trash.Database {
+		return false
+	}
+	return h.cfg.Revisions.Tracks(dbName, collectionName)
+}
+
+// writeWithRevision 先保存original作为历史版本再执行写入，写入失败时撤销该版本
+func (h *Handlers) writeWithRevision(c *gin.Context, dbName, collectionName string, original bson.D, operation string, write func() error) error {
+	if !h.trackRevisions(dbName, collectionName) {
+		return write()
+	}
+
+	ctx := c.Request.Context()
+	revision, err := h.dbService.SaveRevision(ctx, dbName, collectionName, original, operation, h.requestUser(c), h.cfg.Revisions.MaxPerDocument)
+	if err != nil {
+		return err
+	}
+	if err := write(); err != nil {
+		h.dbService.RemoveRevision(context.WithoutCancel(ctx), dbName, collectionName, revision)
+		return err
+	}
+	return nil
+}
+
+// GetRevisions 列出文档的历史版本
+func (h *Handlers) GetRevisions(c *gin.Context) {
+	dbName := c.Param("db")
+	collectionName := c.Param("collection")
+
+	id, err := documentID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	revisions, err := h.dbService.ListRevisions(c.Request.Context(), dbName, collectionName, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"tracked":   h.trackRevisions(dbName, collectionName),
+		"revisions": revisions,
+	})
+}
+
+// GetRevision 获取文档的指定版本，rev为 current 时返回当前版本
+func (h *Handlers) GetRevision(c *gin.Context) {
+	dbName := c.Param("db")
+	collectionName := c.Param("collection")
+
+	id, err := documentID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	document, err := h.revisionDocument(c, dbName, collectionName, id, c.Param("rev"))
+	if err != nil {
+		revisionError(c, err)
+		return
+	}
+	data, err := database.ExtJSON(document, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, data)
+}
+
+// DiffRevisions 对比两个版本的字段差异，to默认为当前版本
+func (h *Handlers) DiffRevisions(c *gin.Context) {
+	dbName := c.Param("db")
+	collectionName := c.Param("collection")
+
+	id, err := documentID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	from, err := h.revisionDocument(c, dbName, collectionName, id, c.Param("rev"))
+	if err != nil {
+		revisionError(c, err)
+		return
+	}
+	to, err := h.revisionDocument(c, dbName, collectionName, id, c.DefaultQuery("to", currentRevision))
+	if err != nil {
+		revisionError(c, err)
+		return
+	}
+
+	changes := database.DiffDocuments(from, to)
+	if changes == nil {
+		changes = []database.FieldChange{}
+	}
+	c.JSON(http.StatusOK, gin.H{"changes": changes})
+}
+
+// RollbackRevision 将文档恢复为指定版本，当前版本会作为新的历史版本保存；需要If-Match与当前版本一致
+func (h *Handlers) RollbackRevision(c *gin.Context) {
+	dbName := c.Param("db")
+	collectionName := c.Param("collection")
+
+	id, err := documentID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	target, err := h.revisionDocument(c, dbName, collectionName, id, c.Param("rev"))
+	if err != nil {
+		revisionError(c, err)
+		return
+	}
+
+	original, ok := h.loadForWrite(c, dbName, collectionName, id)
+	if !ok {
+		return
+	}
+
+	err = h.writeWithRevision(c, dbName, collectionName, original, "rollback", func() error {
+		return h.dbService.ReplaceDocument(c.Request.Context(), dbName, collectionName, id, target, original)
+	})
+	if err != nil {
+		h.writeError(c, dbName, collectionName, id, err, target)
+		return
+	}
+	h.respondWritten(c, dbName, collectionName, id, "Document rolled back successfully")
+}
+
+// revisionDocument 读取指定版本的文档内容
+func (h *Handlers) revisionDocument(c *gin.Context, dbName, collectionName, id, rev string) (bson.D, error) {
+	if rev == currentRevision {
+		return h.dbService.GetDocument(c.Request.Context(), dbName, collectionName, id)
+	}
+
+	number, err := strconv.ParseInt(rev, 10, 64)
+	if err != nil {
+		return nil, errInvalidRevision
+	}
+	revision, err := h.dbService.GetRevision(c.Request.Context(), dbName, collectionName, id, number)
+	if err != nil {
+		return nil, err
+	}
+
+	var document bson.D
+	if err := bson.Unmarshal(revision.Document, &document); err != nil {
+		return nil, err
+	}
+	return document, nil
+}
+
+// revisionError 将版本读取错误映射为HTTP状态码
+func revisionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errInvalidRevision):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, mongo.ErrNoDocuments):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+	default:
+		documentError(c, err)
+	}
+}
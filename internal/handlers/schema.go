@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"m-db-ui/internal/codegen"
+	"m-db-ui/internal/database"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fieldSampleSize 字段补全默认的采样文档数，比结构分析少以便快速返回
+const fieldSampleSize = 200
+
+// FieldPath 用于自动补全的字段路径
+type FieldPath struct {
+	Path     string           `json:"path"`
+	Type     string           `json:"type"`
+	Types    map[string]int64 `json:"types"`
+	Presence float64          `json:"presence"`
+}
+
+// GetFieldPaths 根据采样文档列出字段路径，用于查询条件的自动补全；prefix按前缀过滤（不区分大小写），按出现比例降序排列
+func (h *Handlers) GetFieldPaths(c *gin.Context) {
+	dbName := c.Param("db")
+	collectionName := c.Param("collection")
+	sampleSize, _ := strconv.ParseInt(c.Query("sample"), 10, 64)
+	if sampleSize <= 0 {
+		sampleSize = fieldSampleSize
+	}
+
+	analysis, err := h.dbService.AnalyzeSchema(c.Request.Context(), dbName, collectionName, sampleSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	prefix := strings.ToLower(c.Query("prefix"))
+	fields := []FieldPath{}
+	for _, field := range analysis.Fields {
+		if strings.HasPrefix(strings.ToLower(field.Path), prefix) {
+			fields = append(fields, fieldPath(field))
+		}
+	}
+	sort.SliceStable(fields, func(i, k int) bool { return fields[i].Presence > fields[k].Presence })
+	c.JSON(http.StatusOK, gin.H{"fields": fields, "sampled": analysis.Sampled})
+}
+
+// GetQueryIndexes 获取集合的全文索引和地理空间索引，用于 $text 和地理查询的辅助输入
+func (h *Handlers) GetQueryIndexes(c *gin.Context) {
+	indexes, err := h.dbService.GetQueryIndexes(c.Request.Context(), c.Param("db"), c.Param("collection"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, indexes)
+}
+
+// fieldPath 数组字段的类型取元素类型，以便按元素构造查询条件
+func fieldPath(field *database.FieldStats) FieldPath {
+	path := FieldPath{Path: field.Path, Type: field.DominantType(), Types: field.Types, Presence: field.Presence}
+	if path.Type == "array" && len(field.ArrayTypes) > 0 {
+		elements := &database.FieldStats{Types: field.ArrayTypes}
+		path.Type = "array<" + elements.DominantType() + ">"
+	}
+	return path
+}
+
+// AnalyzeSchema 采样分析集合结构
+func (h *Handlers) AnalyzeSchema(c *gin.Context) {
+	dbName := c.Param("db")
+	collectionName := c.Param("collection")
+	sampleSize, _ := strconv.ParseInt(c.Query("sample"), 10, 64)
+
+	analysis, err := h.dbService.AnalyzeSchema(c.Request.Context(), dbName, collectionName, sampleSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, analysis)
+}
+
+// ExportJSONSchema 根据采样结果导出JSON Schema，flavor=mongodb时导出$jsonSchema格式
+func (h *Handlers) ExportJSONSchema(c *gin.Context) {
+	dbName := c.Param("db")
+	collectionName := c.Param("collection")
+	sampleSize, _ := strconv.ParseInt(c.Query("sample"), 10, 64)
+
+	analysis, err := h.dbService.AnalyzeSchema(c.Request.Context(), dbName, collectionName, sampleSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if c.Query("download") != "" {
+		setAttachment(c, collectionName+".schema.json")
+	}
+	c.IndentedJSON(http.StatusOK, analysis.JSONSchema(c.Query("flavor") == "mongodb"))
+}
+
+// ExportGoStructs 根据采样结果生成Go结构体定义，package和type参数指定包名和类型名
+func (h *Handlers) ExportGoStructs(c *gin.Context) {
+	dbName := c.Param("db")
+	collectionName := c.Param("collection")
+	sampleSize, _ := strconv.ParseInt(c.Query("sample"), 10, 64)
+
+	analysis, err := h.dbService.AnalyzeSchema(c.Request.Context(), dbName, collectionName, sampleSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	code, err := codegen.Structs(analysis, c.Query("package"), c.Query("type"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if c.Query("download") != "" {
+		setAttachment(c, collectionName+".go")
+	}
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(code))
+}
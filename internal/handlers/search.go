@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"m-db-ui/internal/database"
+	"m-db-ui/internal/jobs"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// searchLimit 每个集合默认最多扫描的文档数
+	searchLimit = 10000
+	// searchTimeBudget 每个集合默认的扫描时间上限（秒）
+	searchTimeBudget = 10
+	// searchMaxMatches 默认最多返回的匹配数
+	searchMaxMatches = 500
+	// searchMaxMatchesLimit 请求可指定的最大匹配数
+	searchMaxMatchesLimit = 5000
+)
+
+// SearchPage 全局搜索页面，db 参数指定默认选中的数据库
+func (h *Handlers) SearchPage(c *gin.Context) {
+	databases, err := h.dbService.GetDatabases(c.Request.Context())
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.HTML(http.StatusOK, "search.html", gin.H{
+		"title":           "全局搜索",
+		"databases":       databases,
+		"dbName":          c.Query("db"),
+		"limit":           searchLimit,
+		"timeBudget":      searchTimeBudget,
+		"maxMatches":      searchMaxMatches,
+		"maxMatchesLimit": searchMaxMatchesLimit,
+	})
+}
+
+// Search 在选定的数据库和集合中搜索值，以后台任务运行；每个匹配以 match 事件推送，
+// 任务结果中只包含匹配数和各集合的扫描情况
+func (h *Handlers) Search(c *gin.Context) {
+	var req struct {
+		Mode       string   `json:"mode"`
+		Value      string   `json:"value" binding:"required"`
+		IgnoreCase bool     `json:"ignoreCase"`
+		Namespaces []string `json:"namespaces"`
+		Limit      *int64   `json:"limit"`
+		TimeBudget *float64 `json:"timeBudget"`
+		MaxMatches *int     `json:"maxMatches"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	opts := database.SearchOptions{
+		Mode:       req.Mode,
+		Value:      req.Value,
+		IgnoreCase: req.IgnoreCase,
+		Limit:      searchLimit,
+		TimeBudget: searchTimeBudget * time.Second,
+		MaxMatches: searchMaxMatches,
+	}
+	if opts.Mode == "" {
+		opts.Mode = database.SearchExact
+	}
+	if req.Limit != nil {
+		opts.Limit = *req.Limit
+	}
+	if req.TimeBudget != nil {
+		opts.TimeBudget = time.Duration(*req.TimeBudget * float64(time.Second))
+	}
+	if req.MaxMatches != nil {
+		opts.MaxMatches = *req.MaxMatches
+	}
+	if opts.Limit < 0 || opts.TimeBudget < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit and timeBudget must not be negative"})
+		return
+	}
+	if opts.MaxMatches < 1 || opts.MaxMatches > searchMaxMatchesLimit {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("maxMatches must be between 1 and %d", searchMaxMatchesLimit)})
+		return
+	}
+	if err := opts.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 搜索全部数据库时不包括回收站
+	var excluded []string
+	if h.cfg.Trash.Enabled {
+		excluded = append(excluded, h.cfg.Trash.Database)
+	}
+
+	params := map[string]interface{}{
+		"mode":       opts.Mode,
+		"value":      opts.Value,
+		"ignoreCase": opts.IgnoreCase,
+		"namespaces": req.Namespaces,
+		"limit":      opts.Limit,
+		"timeBudget": opts.TimeBudget.Seconds(),
+		"maxMatches": opts.MaxMatches,
+	}
+	description := fmt.Sprintf("search %q", opts.Value)
+
+	job := h.jobManager.Submit("search", description, params, func(ctx context.Context, r *jobs.Reporter) (interface{}, error) {
+		namespaces, err := h.dbService.SearchNamespaces(ctx, req.Namespaces, excluded)
+		if err != nil {
+			return nil, err
+		}
+		result, err := h.dbService.Search(ctx, namespaces, opts, func(m database.SearchMatch) {
+			r.Emit("match", m)
+		}, func(done, total int64, current database.Namespace) {
+			r.SetTotal(total)
+			r.SetDone(done)
+			if done < total {
+				r.SetMessage(current.String())
+			} else {
+				r.SetMessage("")
+			}
+		})
+		if result == nil {
+			return nil, err
+		}
+		// 取消时也返回已扫描部分的统计
+		return result, err
+	})
+
+	c.JSON(http.StatusAccepted, job)
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"m-db-ui/internal/shell"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// shellUpgrader 使用默认的同源检查，只允许本站页面建立连接
+var shellUpgrader = websocket.Upgrader{}
+
+// shellMessage 脚本控制台WebSocket消息。
+// 客户端发送 execute(code)、cancel；服务端返回 ready(db)、output(text)、result(text, db, durationMs)、error(text, durationMs)
+type shellMessage struct {
+	Type       string `json:"type"`
+	Code       string `json:"code,omitempty"`
+	Text       string `json:"text,omitempty"`
+	DB         string `json:"db,omitempty"`
+	DurationMS int64  `json:"durationMs,omitempty"`
+}
+
+// ShellPage JavaScript脚本控制台页面
+func (h *Handlers) ShellPage(c *gin.Context) {
+	databases, err := h.dbService.GetDatabases(c.Request.Context())
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	role := h.commandRole()
+	c.HTML(http.StatusOK, "shell.html", gin.H{
+		"title":     "脚本控制台",
+		"enabled":   h.cfg.Shell.Enabled,
+		"allowed":   h.cfg.Shell.Allows(role),
+		"role":      role,
+		"timeout":   h.cfg.Shell.Timeout.String(),
+		"databases": databases,
+		"dbName":    c.DefaultQuery("db", "test"),
+	})
+}
+
+// ShellSocket 脚本控制台的WebSocket连接，每个连接对应一个独立的脚本会话，断开时取消正在执行的脚本
+func (h *Handlers) ShellSocket(c *gin.Context) {
+	if !h.cfg.Shell.Enabled {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Shell is disabled"})
+		return
+	}
+	role := h.commandRole()
+	if !h.cfg.Shell.Allows(role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("Shell is not allowed for role %q", role)})
+		return
+	}
+
+	conn, err := shellUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// Upgrade已经向客户端返回了错误
+		c.Error(err)
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var writeMutex sync.Mutex
+	send := func(message shellMessage) {
+		writeMutex.Lock()
+		defer writeMutex.Unlock()
+		conn.WriteJSON(message)
+	}
+
+	session := shell.NewSession(h.dbService, shell.Options{
+		Database: c.Query("db"),
+		Timeout:  h.cfg.Shell.Timeout,
+		AllowCommand: func(name string) bool {
+			return h.cfg.Commands.Allows(role, name)
+		},
+	})
+	send(shellMessage{Type: "ready", DB: session.Database()})
+
+	var runningMutex sync.Mutex
+	running := false
+	for {
+		var message shellMessage
+		if err := conn.ReadJSON(&message); err != nil {
+			session.Cancel()
+			return
+		}
+
+		switch message.Type {
+		case "execute":
+			runningMutex.Lock()
+			busy := running
+			running = true
+			runningMutex.Unlock()
+			if busy {
+				send(shellMessage{Type: "error", Text: "another script is still running"})
+				continue
+			}
+
+			go func(code string) {
+				defer func() {
+					runningMutex.Lock()
+					running = false
+					runningMutex.Unlock()
+				}()
+
+				started := time.Now()
+				result, err := session.Execute(ctx, code, func(text string) {
+					send(shellMessage{Type: "output", Text: text})
+				})
+				duration := time.Since(started).Milliseconds()
+				if err != nil {
+					send(shellMessage{Type: "error", Text: err.Error(), DurationMS: duration})
+					return
+				}
+				send(shellMessage{Type: "result", Text: result, DB: session.Database(), DurationMS: duration})
+			}(message.Code)
+		case "cancel":
+			session.Cancel()
+		default:
+			send(shellMessage{Type: "error", Text: fmt.Sprintf("unknown message type %q", message.Type)})
+		}
+	}
+}
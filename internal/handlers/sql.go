@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"m-db-ui/internal/database"
+	"m-db-ui/internal/sqlquery"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// QuerySQL 将SQL SELECT语句翻译为MongoDB查询并执行，集合取自FROM子句。
+// 返回翻译结果（find条件或聚合管道）、mongosh写法、可用于集合页面的查询条件和当前页的文档；dryRun为true时只翻译不执行
+func (h *Handlers) QuerySQL(c *gin.Context) {
+	dbName := c.Param("db")
+
+	var req struct {
+		SQL       string `json:"sql" binding:"required"`
+		Page      int64  `json:"page"`
+		Limit     int64  `json:"limit"`
+		MaxTimeMS int64  `json:"maxTimeMS"`
+		DryRun    bool   `json:"dryRun"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	translation, err := sqlquery.Translate(req.SQL)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// find查询带有SKIP/LIMIT时无法用集合页面的分页表示，改用等价的聚合管道
+	kind := "find"
+	native := bson.D{{Key: "filter", Value: translation.Filter}}
+	query := database.Query{
+		Filter:     translation.Filter,
+		Sort:       translation.Sort,
+		Projection: translation.Projection,
+	}
+	if translation.Aggregate() {
+		kind = "aggregate"
+		native = bson.D{{Key: "pipeline", Value: translation.Pipeline}}
+		query = database.Query{Pipeline: translation.Pipeline}
+	} else {
+		if len(translation.Sort) > 0 {
+			native = append(native, bson.E{Key: "sort", Value: translation.Sort})
+		}
+		if len(translation.Projection) > 0 {
+			native = append(native, bson.E{Key: "projection", Value: translation.Projection})
+		}
+		if translation.Skip > 0 {
+			native = append(native, bson.E{Key: "skip", Value: translation.Skip})
+		}
+		if translation.Limit > 0 {
+			native = append(native, bson.E{Key: "limit", Value: translation.Limit})
+		}
+		if translation.Skip > 0 || translation.Limit > 0 {
+			query = database.Query{Pipeline: translation.Stages()}
+		}
+	}
+
+	response := gin.H{
+		"collection":  translation.Collection,
+		"type":        kind,
+		"translation": database.ExtJSONValue(native),
+		"shell":       translation.Shell(),
+		"query":       historyQuery(query),
+	}
+	if req.DryRun {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	req.Limit = h.clampLimit(req.Limit)
+
+	started := time.Now()
+	documents, err := h.dbService.QueryDocuments(c.Request.Context(), dbName, translation.Collection, query, req.Page, req.Limit, time.Duration(req.MaxTimeMS)*time.Millisecond)
+	h.recordHistory(c, dbName, translation.Collection, query, time.Since(started), documents, err)
+	if err != nil {
+		response["error"] = err.Error()
+		c.JSON(http.StatusInternalServerError, response)
+		return
+	}
+	response["documents"] = documents.Documents
+	response["total"] = documents.Total
+	response["page"] = documents.Page
+	response["limit"] = documents.Limit
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"errors"
+	"m-db-ui/internal/database"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// trashListLimit 回收站列表默认返回的条目数
+const trashListLimit = 200
+
+// trash 返回回收站，未启用时返回nil
+func (h *Handlers) trash() *database.Trash {
+	if !h.cfg.Trash.Enabled {
+		return nil
+	}
+	return h.dbService.Trash(h.cfg.Trash.Database)
+}
+
+// useTrash 删除操作是否先移入回收站：回收站未启用、请求指定permanent=true或删除的是回收站自身的数据时直接删除
+func (h *Handlers) useTrash(c *gin.Context, dbName string) bool {
+	if !h.cfg.Trash.Enabled || dbName == h.cfg.Trash.Database {
+		return false
+	}
+	permanent, _ := strconv.ParseBool(c.Query("permanent"))
+	return !permanent
+}
+
+// TrashPage 回收站页面
+func (h *Handlers) TrashPage(c *gin.Context) {
+	c.HTML(http.StatusOK, "trash.html", gin.H{
+		"title":         "回收站",
+		"enabled":       h.cfg.Trash.Enabled,
+		"database":      h.cfg.Trash.Database,
+		"retentionDays": h.cfg.Trash.RetentionDays,
+	})
+}
+
+// GetTrash 列出回收站条目，可按数据库过滤
+func (h *Handlers) GetTrash(c *gin.Context) {
+	trash := h.trash()
+	if trash == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Recycle bin is disabled"})
+		return
+	}
+
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", strconv.Itoa(trashListLimit)), 10, 64)
+	if err != nil || limit < 1 {
+		limit = trashListLimit
+	}
+
+	entries, err := trash.List(c.Request.Context(), c.Query("db"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"entries": entries, "retentionDays": h.cfg.Trash.RetentionDays})
+}
+
+// RestoreTrash 将回收站条目恢复到原位置
+func (h *Handlers) RestoreTrash(c *gin.Context) {
+	trash := h.trash()
+	if trash == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Recycle bin is disabled"})
+		return
+	}
+
+	entry, err := trash.Restore(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		trashError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Restored successfully", "entry": entry})
+}
+
+// PurgeTrash 彻底删除回收站条目
+func (h *Handlers) PurgeTrash(c *gin.Context) {
+	trash := h.trash()
+	if trash == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Recycle bin is disabled"})
+		return
+	}
+
+	if err := trash.Purge(c.Request.Context(), c.Param("id")); err != nil {
+		trashError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Purged successfully"})
+}
+
+// EmptyTrash 清空回收站
+func (h *Handlers) EmptyTrash(c *gin.Context) {
+	trash := h.trash()
+	if trash == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Recycle bin is disabled"})
+		return
+	}
+
+	purged, err := trash.Sweep(c.Request.Context(), time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "purged": purged})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Recycle bin emptied successfully", "purged": purged})
+}
+
+// trashError 将回收站操作的错误映射为HTTP状态码
+func trashError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Recycle bin entry not found"})
+	case errors.Is(err, database.ErrRestoreConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
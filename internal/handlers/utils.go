@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"m-db-ui/internal/database"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// bindExtJSON 将请求体按Extended JSON解析，保留$date、$oid等类型信息；目标结构体使用bson标签
+func bindExtJSON(c *gin.Context, v interface{}) error {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return err
+	}
+	if len(body) == 0 {
+		return fmt.Errorf("request body is empty")
+	}
+	return bson.UnmarshalExtJSON(body, false, v)
+}
+
+// documentID 从路径参数中取出文档ID，兼容URL编码和 ObjectID("...") 包装
+func documentID(c *gin.Context) (string, error) {
+	id, err := url.QueryUnescape(c.Param("id"))
+	if err != nil {
+		return "", fmt.Errorf("Invalid ID format")
+	}
+
+	// 移除ObjectID()包装，如果存在
+	if len(id) > 9 && id[:9] == "ObjectID(" && id[len(id)-1] == ')' {
+		id = id[9 : len(id)-1]
+		// 移除可能的引号
+		id = strings.Trim(id, `"`)
+	}
+	return id, nil
+}
+
+// documentError 将单文档操作的错误映射为HTTP状态码
+func documentError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+	case errors.Is(err, database.ErrImmutableID):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, database.ErrPatchTestFailed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// requestUser 识别发起请求的用户：请求来自受信任的反向代理时使用其传入的用户名，否则使用客户端IP
+func (h *Handlers) requestUser(c *gin.Context) string {
+	if !h.cfg.Server.TrustsProxy(c.RemoteIP()) {
+		return c.ClientIP()
+	}
+	for _, header := range []string{"X-Remote-User", "X-Forwarded-User"} {
+		if user := c.GetHeader(header); user != "" {
+			return user
+		}
+	}
+	return c.ClientIP()
+}
+
+// setAttachment 设置下载文件名，按RFC 2231编码引号和非ASCII字符
+func setAttachment(c *gin.Context, filename string) {
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"errors"
+	"m-db-ui/internal/database"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// GetValidator 获取集合的校验规则
+func (h *Handlers) GetValidator(c *gin.Context) {
+	info, err := h.dbService.GetValidator(c.Request.Context(), c.Param("db"), c.Param("collection"))
+	if errors.Is(err, database.ErrCollectionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	validator, err := database.ExtJSON(info.Validator, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"validator":        validator,
+		"validationLevel":  info.ValidationLevel,
+		"validationAction": info.ValidationAction,
+	})
+}
+
+// UpdateValidator 修改集合的校验规则
+func (h *Handlers) UpdateValidator(c *gin.Context) {
+	var info database.ValidatorInfo
+	if err := bindExtJSON(c, &info); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.dbService.UpdateValidator(c.Request.Context(), c.Param("db"), c.Param("collection"), &info)
+	var cmdErr mongo.CommandError
+	switch {
+	case errors.Is(err, database.ErrCollectionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, database.ErrInvalidValidator), errors.As(err, &cmdErr):
+		// 取值无效或$jsonSchema被服务端拒绝，属于请求错误
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Validator updated successfully"})
+}
+
+// GenerateValidator 根据采样数据生成$jsonSchema校验规则
+func (h *Handlers) GenerateValidator(c *gin.Context) {
+	sampleSize, _ := strconv.ParseInt(c.Query("sample"), 10, 64)
+
+	validator, err := h.dbService.GenerateValidator(c.Request.Context(), c.Param("db"), c.Param("collection"), sampleSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	data, err := database.ExtJSON(validator, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"validator": data})
+}
+
+// ScanValidator 试运行校验规则，列出不满足规则的现有文档
+func (h *Handlers) ScanValidator(c *gin.Context) {
+	var req struct {
+		Validator bson.M `bson:"validator"`
+		Limit     int64  `bson:"limit"`
+	}
+	if err := bindExtJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(req.Validator) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "validator is required"})
+		return
+	}
+
+	scan, err := h.dbService.ScanValidator(c.Request.Context(), c.Param("db"), c.Param("collection"), req.Validator, h.clampLimit(req.Limit))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, scan)
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"m-db-ui/internal/database"
+	"net/http"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// watchHeartbeat 没有变更事件时发送心跳的间隔，避免代理断开空闲连接
+const watchHeartbeat = 15 * time.Second
+
+// WatchChanges 通过SSE推送变更流事件。
+// 路由中不带:db时监听整个集群，不带:collection时监听整个数据库。
+// 查询参数：match（Extended JSON格式的$match条件）、fullDocument（默认updateLookup，传"default"关闭）、
+// resumeAfter（resume token）；浏览器重连时发送的Last-Event-ID同样作为resume token
+func (h *Handlers) WatchChanges(c *gin.Context) {
+	dbName := c.Param("db")
+	collectionName := c.Param("collection")
+
+	opts := database.WatchOptions{
+		UpdateLookup: c.DefaultQuery("fullDocument", "updateLookup") == "updateLookup",
+		MaxAwaitTime: time.Second,
+	}
+
+	if match := c.Query("match"); match != "" {
+		if err := bson.UnmarshalExtJSON([]byte(match), false, &opts.Match); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid match filter: " + err.Error()})
+			return
+		}
+	}
+
+	resumeToken := c.Query("resumeAfter")
+	if lastEventID := c.GetHeader("Last-Event-ID"); lastEventID != "" {
+		resumeToken = lastEventID
+	}
+	if resumeToken != "" {
+		var token bson.M
+		if err := bson.UnmarshalExtJSON([]byte(resumeToken), false, &token); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid resume token: " + err.Error()})
+			return
+		}
+		opts.ResumeAfter = token
+	}
+
+	ctx := c.Request.Context()
+
+	// 打开变更流失败时在开始SSE响应之前返回错误状态码，EventSource收到非200响应后不再重连。
+	// 服务端拒绝（如不是副本集、resume token无效）时返回400
+	stream, err := h.dbService.Watch(ctx, dbName, collectionName, opts)
+	if err != nil {
+		var cmdErr mongo.CommandError
+		if errors.As(err, &cmdErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": cmdErr.Message, "code": cmdErr.Code, "codeName": cmdErr.Name})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer stream.Close(ctx)
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+
+	c.SSEvent("ready", gin.H{"db": dbName, "collection": collectionName})
+	c.Writer.Flush()
+
+	lastSent := time.Now()
+	c.Stream(func(w io.Writer) bool {
+		if stream.TryNext(ctx) {
+			var event bson.M
+			if err := stream.Decode(&event); err != nil {
+				c.SSEvent("error", gin.H{"error": err.Error()})
+				return false
+			}
+			token, _ := bson.MarshalExtJSON(stream.ResumeToken(), false, false)
+			c.Render(-1, sse.Event{Id: string(token), Event: "change", Data: event})
+			lastSent = time.Now()
+			return true
+		}
+
+		if err := stream.Err(); err != nil {
+			if ctx.Err() == nil {
+				c.SSEvent("error", gin.H{"error": err.Error()})
+			}
+			return false
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+
+		if time.Since(lastSent) >= watchHeartbeat {
+			c.SSEvent("heartbeat", gin.H{"time": time.Now().Unix()})
+			lastSent = time.Now()
+		}
+		return true
+	})
+}
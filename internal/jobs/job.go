@@ -0,0 +1,113 @@
+package jobs
+
+import (
+	"context"
+	"time"
+)
+
+// Status 任务状态
+type Status string
+
+const (
+	StatusPending     Status = "pending"
+	StatusRunning     Status = "running"
+	StatusCompleted   Status = "completed"
+	StatusFailed      Status = "failed"
+	StatusCancelled   Status = "cancelled"
+	StatusInterrupted Status = "interrupted"
+)
+
+// Finished 任务是否已结束
+func (s Status) Finished() bool {
+	switch s {
+	case StatusCompleted, StatusFailed, StatusCancelled, StatusInterrupted:
+		return true
+	}
+	return false
+}
+
+// Progress 任务进度
+type Progress struct {
+	Done       int64   `json:"done"`
+	Total      int64   `json:"total"`
+	Percent    float64 `json:"percent"`
+	ETASeconds int64   `json:"etaSeconds,omitempty"`
+}
+
+// Job 后台任务
+type Job struct {
+	ID          string                 `json:"id"`
+	Type        string                 `json:"type"`
+	Description string                 `json:"description,omitempty"`
+	Params      map[string]interface{} `json:"params,omitempty"`
+	Status      Status                 `json:"status"`
+	Progress    Progress               `json:"progress"`
+	Message     string                 `json:"message,omitempty"`
+	Error       string                 `json:"error,omitempty"`
+	Result      interface{}            `json:"result,omitempty"`
+	CreatedAt   int64                  `json:"createdAt"`
+	StartedAt   int64                  `json:"startedAt,omitempty"`
+	FinishedAt  int64                  `json:"finishedAt,omitempty"`
+}
+
+// snapshot 复制任务，避免调用方读取时与运行中的任务产生数据竞争
+func (j *Job) snapshot() *Job {
+	cp := *j
+	cp.Progress = j.progress()
+	return &cp
+}
+
+// progress 根据已运行时间计算百分比和预计剩余时间
+func (j *Job) progress() Progress {
+	p := j.Progress
+	if p.Total <= 0 {
+		return p
+	}
+	p.Percent = float64(p.Done) * 100 / float64(p.Total)
+	if j.Status == StatusRunning && j.StartedAt > 0 && p.Done > 0 && p.Done < p.Total {
+		elapsed := time.Since(time.Unix(j.StartedAt, 0)).Seconds()
+		p.ETASeconds = int64(elapsed / float64(p.Done) * float64(p.Total-p.Done))
+	}
+	return p
+}
+
+// Event 任务事件，通过SSE推送给客户端
+type Event struct {
+	Type string      `json:"type"`
+	Job  *Job        `json:"job"`
+	Data interface{} `json:"data,omitempty"`
+}
+
+// Func 任务执行函数，ctx在任务被取消时结束
+type Func func(ctx context.Context, r *Reporter) (interface{}, error)
+
+// Reporter 供任务函数汇报进度
+type Reporter struct {
+	manager *Manager
+	id      string
+}
+
+// SetTotal 设置任务总量
+func (r *Reporter) SetTotal(total int64) {
+	r.manager.update(r.id, func(j *Job) { j.Progress.Total = total })
+}
+
+// Add 增加已完成数量
+func (r *Reporter) Add(n int64) {
+	r.manager.update(r.id, func(j *Job) { j.Progress.Done += n })
+}
+
+// SetDone 设置已完成数量
+func (r *Reporter) SetDone(done int64) {
+	r.manager.update(r.id, func(j *Job) { j.Progress.Done = done })
+}
+
+// SetMessage 设置当前状态描述
+func (r *Reporter) SetMessage(message string) {
+	r.manager.update(r.id, func(j *Job) { j.Message = message })
+}
+
+// Emit 推送自定义事件（例如中间结果），不会持久化
+func (r *Reporter) Emit(eventType string, data interface{}) {
+	r.manager.emit(r.id, eventType, data)
+}
@@ -0,0 +1,361 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// maxConcurrent 同时运行的任务数上限，其余任务排队等待
+	maxConcurrent = 4
+	// maxFinished 保留的已结束任务数量，超出后删除最早的记录
+	maxFinished = 200
+	// saveInterval 进度更新时写入文件的最小间隔
+	saveInterval = time.Second
+)
+
+// Manager 后台任务管理器，任务表持久化在JSON文件中
+type Manager struct {
+	jobs        map[string]*Job
+	cancels     map[string]context.CancelFunc
+	subscribers map[string]map[chan Event]struct{}
+	slots       chan struct{}
+	mutex       sync.RWMutex
+	filePath    string
+	lastSave    time.Time
+	seq         int64
+}
+
+// NewManager 创建任务管理器
+func NewManager(filePath string) *Manager {
+	return &Manager{
+		jobs:        make(map[string]*Job),
+		cancels:     make(map[string]context.CancelFunc),
+		subscribers: make(map[string]map[chan Event]struct{}),
+		slots:       make(chan struct{}, maxConcurrent),
+		filePath:    filePath,
+	}
+}
+
+// Load 加载任务表，上次运行时未结束的任务标记为已中断
+func (m *Manager) Load() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	data, err := os.ReadFile(m.filePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var jobs []*Job
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+	for _, job := range jobs {
+		if !job.Status.Finished() {
+			job.Status = StatusInterrupted
+			job.Error = "interrupted by server restart"
+			job.FinishedAt = now
+		}
+		m.jobs[job.ID] = job
+	}
+
+	return m.saveJobs()
+}
+
+// saveJobs 保存任务表，调用方需持有锁
+func (m *Manager) saveJobs() error {
+	jobs := make([]*Job, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, k int) bool { return jobs[i].CreatedAt < jobs[k].CreatedAt })
+
+	data, err := json.MarshalIndent(jobs, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	m.lastSave = time.Now()
+	return os.WriteFile(m.filePath, data, 0644)
+}
+
+// persist 保存任务表，失败时只记录日志（任务本身不受影响），调用方需持有锁
+func (m *Manager) persist() {
+	if err := m.saveJobs(); err != nil {
+		log.Printf("Failed to save jobs to %s: %v", m.filePath, err)
+	}
+}
+
+// Submit 提交任务并在后台执行
+func (m *Manager) Submit(jobType, description string, params map[string]interface{}, fn Func) *Job {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	m.mutex.Lock()
+	m.seq++
+	job := &Job{
+		ID:          fmt.Sprintf("job_%d_%d", time.Now().UnixNano(), m.seq),
+		Type:        jobType,
+		Description: description,
+		Params:      params,
+		Status:      StatusPending,
+		CreatedAt:   time.Now().Unix(),
+	}
+	m.jobs[job.ID] = job
+	m.cancels[job.ID] = cancel
+	m.prune()
+	m.persist()
+	snapshot := job.snapshot()
+	m.mutex.Unlock()
+
+	go m.run(ctx, job.ID, fn)
+	return snapshot
+}
+
+// run 等待空闲槽位后执行任务
+func (m *Manager) run(ctx context.Context, id string, fn Func) {
+	select {
+	case m.slots <- struct{}{}:
+		defer func() { <-m.slots }()
+	case <-ctx.Done():
+		m.finish(id, nil, ctx.Err())
+		return
+	}
+
+	m.setStatus(id, func(j *Job) {
+		j.Status = StatusRunning
+		j.StartedAt = time.Now().Unix()
+	})
+
+	var (
+		result interface{}
+		err    error
+	)
+	func() {
+		defer func() {
+			if p := recover(); p != nil {
+				err = fmt.Errorf("job panicked: %v", p)
+			}
+		}()
+		result, err = fn(ctx, &Reporter{manager: m, id: id})
+	}()
+
+	if err == nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	m.finish(id, result, err)
+}
+
+// finish 记录任务结果并关闭订阅
+func (m *Manager) finish(id string, result interface{}, err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	job, exists := m.jobs[id]
+	if !exists {
+		return
+	}
+
+	job.FinishedAt = time.Now().Unix()
+	job.Result = result
+	switch {
+	case err == nil:
+		job.Status = StatusCompleted
+		if job.Progress.Total > 0 {
+			job.Progress.Done = job.Progress.Total
+		}
+	case errors.Is(err, context.Canceled):
+		job.Status = StatusCancelled
+		job.Error = "cancelled"
+	default:
+		job.Status = StatusFailed
+		job.Error = err.Error()
+	}
+
+	if cancel, ok := m.cancels[id]; ok {
+		cancel()
+		delete(m.cancels, id)
+	}
+
+	m.broadcast(id, Event{Type: "status", Job: job.snapshot()})
+	for ch := range m.subscribers[id] {
+		close(ch)
+	}
+	delete(m.subscribers, id)
+	m.persist()
+}
+
+// setStatus 修改任务状态并立即持久化
+func (m *Manager) setStatus(id string, fn func(j *Job)) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	job, exists := m.jobs[id]
+	if !exists {
+		return
+	}
+	fn(job)
+	m.broadcast(id, Event{Type: "status", Job: job.snapshot()})
+	m.persist()
+}
+
+// update 修改任务进度，按间隔节流写入文件
+func (m *Manager) update(id string, fn func(j *Job)) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	job, exists := m.jobs[id]
+	if !exists || job.Status.Finished() {
+		return
+	}
+	fn(job)
+	m.broadcast(id, Event{Type: "progress", Job: job.snapshot()})
+	if time.Since(m.lastSave) >= saveInterval {
+		m.persist()
+	}
+}
+
+// emit 推送自定义事件
+func (m *Manager) emit(id, eventType string, data interface{}) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	job, exists := m.jobs[id]
+	if !exists || job.Status.Finished() {
+		return
+	}
+	m.broadcast(id, Event{Type: eventType, Job: job.snapshot(), Data: data})
+}
+
+// broadcast 向订阅者发送事件，调用方需持有锁；订阅者处理不及时则丢弃事件
+func (m *Manager) broadcast(id string, event Event) {
+	for ch := range m.subscribers[id] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// prune 删除超出保留数量的已结束任务，调用方需持有锁
+func (m *Manager) prune() {
+	var finished []*Job
+	for _, job := range m.jobs {
+		if job.Status.Finished() {
+			finished = append(finished, job)
+		}
+	}
+	if len(finished) <= maxFinished {
+		return
+	}
+	sort.Slice(finished, func(i, k int) bool { return finished[i].CreatedAt < finished[k].CreatedAt })
+	for _, job := range finished[:len(finished)-maxFinished] {
+		delete(m.jobs, job.ID)
+	}
+}
+
+// List 获取所有任务，按创建时间倒序
+func (m *Manager) List() []*Job {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	jobs := make([]*Job, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		jobs = append(jobs, job.snapshot())
+	}
+	sort.Slice(jobs, func(i, k int) bool { return jobs[i].CreatedAt > jobs[k].CreatedAt })
+	return jobs
+}
+
+// Get 获取指定任务
+func (m *Manager) Get(id string) (*Job, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	job, exists := m.jobs[id]
+	if !exists {
+		return nil, fmt.Errorf("job not found")
+	}
+	return job.snapshot(), nil
+}
+
+// Cancel 取消未结束的任务
+func (m *Manager) Cancel(id string) error {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	job, exists := m.jobs[id]
+	if !exists {
+		return fmt.Errorf("job not found")
+	}
+	if job.Status.Finished() {
+		return fmt.Errorf("job already finished")
+	}
+	if cancel, ok := m.cancels[id]; ok {
+		cancel()
+	}
+	return nil
+}
+
+// Remove 删除已结束任务的记录
+func (m *Manager) Remove(id string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	job, exists := m.jobs[id]
+	if !exists {
+		return fmt.Errorf("job not found")
+	}
+	if !job.Status.Finished() {
+		return fmt.Errorf("job is still running")
+	}
+	delete(m.jobs, id)
+	return m.saveJobs()
+}
+
+// Subscribe 订阅任务事件，任务结束时通道关闭；返回的函数用于取消订阅
+func (m *Manager) Subscribe(id string) (<-chan Event, func(), error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	job, exists := m.jobs[id]
+	if !exists {
+		return nil, nil, fmt.Errorf("job not found")
+	}
+
+	ch := make(chan Event, 64)
+	ch <- Event{Type: "status", Job: job.snapshot()}
+	if job.Status.Finished() {
+		close(ch)
+		return ch, func() {}, nil
+	}
+
+	if m.subscribers[id] == nil {
+		m.subscribers[id] = make(map[chan Event]struct{})
+	}
+	m.subscribers[id][ch] = struct{}{}
+
+	unsubscribe := func() {
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+		if subs, ok := m.subscribers[id]; ok {
+			if _, ok := subs[ch]; ok {
+				delete(subs, ch)
+				close(ch)
+			}
+		}
+	}
+	return ch, unsubscribe, nil
+}
@@ -0,0 +1,40 @@
+package queries
+
+import "encoding/json"
+
+// Query 查询条件，各字段为relaxed Extended JSON，便于前端直接回填到编辑器
+type Query struct {
+	Filter     json.RawMessage `json:"filter,omitempty"`
+	Sort       json.RawMessage `json:"sort,omitempty"`
+	Projection json.RawMessage `json:"projection,omitempty"`
+	Pipeline   json.RawMessage `json:"pipeline,omitempty"`
+}
+
+// HistoryEntry 一次查询的执行记录
+type HistoryEntry struct {
+	ID          string `json:"id"`
+	User        string `json:"user"`
+	Connection  string `json:"connection"`
+	Database    string `json:"database"`
+	Collection  string `json:"collection"`
+	Query       Query  `json:"query"`
+	ExecutedAt  int64  `json:"executedAt"`
+	DurationMS  int64  `json:"durationMs"`
+	ResultCount int64  `json:"resultCount"`
+	Error       string `json:"error,omitempty"`
+}
+
+// SavedQuery 命名保存的查询，Shared为true时对所有用户可见
+type SavedQuery struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Owner       string `json:"owner"`
+	Shared      bool   `json:"shared"`
+	Connection  string `json:"connection,omitempty"`
+	Database    string `json:"database"`
+	Collection  string `json:"collection"`
+	Query       Query  `json:"query"`
+	CreatedAt   int64  `json:"createdAt"`
+	UpdatedAt   int64  `json:"updatedAt"`
+}
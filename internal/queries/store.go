@@ -0,0 +1,269 @@
+package queries
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// maxHistoryPerUser 每个用户保留的查询历史条数，超出后删除最早的记录
+	maxHistoryPerUser = 200
+	// historySaveInterval 记录查询历史后延迟写入文件的时间，期间的多次查询合并为一次写入
+	historySaveInterval = 2 * time.Second
+)
+
+var (
+	// ErrNotFound 保存的查询不存在
+	ErrNotFound = errors.New("saved query not found")
+	// ErrForbidden 只有创建者可以修改或删除保存的查询
+	ErrForbidden = errors.New("only the owner can modify this saved query")
+)
+
+// Store 查询历史和保存的查询，持久化在JSON文件中
+type Store struct {
+	history  []*HistoryEntry
+	saved    map[string]*SavedQuery
+	mutex    sync.RWMutex
+	filePath string
+	seq      int64
+	dirty    bool        // 有未写入文件的查询历史
+	timer    *time.Timer // 等待写入查询历史的定时器
+}
+
+// storeFile 持久化文件的结构
+type storeFile struct {
+	History []*HistoryEntry `json:"history"`
+	Saved   []*SavedQuery   `json:"saved"`
+}
+
+// NewStore 创建查询存储
+func NewStore(filePath string) *Store {
+	return &Store{
+		saved:    make(map[string]*SavedQuery),
+		filePath: filePath,
+	}
+}
+
+// Load 加载查询历史和保存的查询
+func (s *Store) Load() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	data, err := os.ReadFile(s.filePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var file storeFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return err
+	}
+
+	s.history = file.History
+	for _, query := range file.Saved {
+		s.saved[query.ID] = query
+	}
+	return nil
+}
+
+// save 保存到文件，调用方需持有锁
+func (s *Store) save() error {
+	file := storeFile{History: s.history, Saved: make([]*SavedQuery, 0, len(s.saved))}
+	for _, query := range s.saved {
+		file.Saved = append(file.Saved, query)
+	}
+	sort.Slice(file.Saved, func(i, k int) bool { return file.Saved[i].CreatedAt < file.Saved[k].CreatedAt })
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	s.dirty = false
+	return os.WriteFile(s.filePath, data, 0644)
+}
+
+// flushHistory 定时器到期时写入查询历史，写入失败只记录日志
+func (s *Store) flushHistory() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.timer = nil
+	if !s.dirty {
+		return
+	}
+	if err := s.save(); err != nil {
+		log.Printf("Failed to save query history to %s: %v", s.filePath, err)
+	}
+}
+
+// nextID 生成ID，调用方需持有锁
+func (s *Store) nextID(prefix string) string {
+	s.seq++
+	return fmt.Sprintf("%s_%d_%d", prefix, time.Now().UnixNano(), s.seq)
+}
+
+// AddHistory 记录一次查询；查询历史按historySaveInterval批量写入文件，不阻塞查询
+func (s *Store) AddHistory(entry *HistoryEntry) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	entry.ID = s.nextID("h")
+	if entry.ExecutedAt == 0 {
+		entry.ExecutedAt = time.Now().Unix()
+	}
+	s.history = append(s.history, entry)
+
+	// 只保留该用户最近的记录
+	count := 0
+	for i := len(s.history) - 1; i >= 0; i-- {
+		if s.history[i].User != entry.User {
+			continue
+		}
+		count++
+		if count > maxHistoryPerUser {
+			s.history = append(s.history[:i], s.history[i+1:]...)
+		}
+	}
+
+	s.dirty = true
+	if s.timer == nil {
+		s.timer = time.AfterFunc(historySaveInterval, s.flushHistory)
+	}
+}
+
+// History 按时间倒序返回用户的查询历史，dbName、collectionName不为空时按其过滤
+func (s *Store) History(user, dbName, collectionName string, limit int) []*HistoryEntry {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	entries := []*HistoryEntry{}
+	for i := len(s.history) - 1; i >= 0 && (limit <= 0 || len(entries) < limit); i-- {
+		entry := s.history[i]
+		if entry.User != user || !matches(entry.Database, entry.Collection, dbName, collectionName) {
+			continue
+		}
+		copied := *entry
+		entries = append(entries, &copied)
+	}
+	return entries
+}
+
+// ClearHistory 清空用户的查询历史
+func (s *Store) ClearHistory(user string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	kept := s.history[:0]
+	for _, entry := range s.history {
+		if entry.User != user {
+			kept = append(kept, entry)
+		}
+	}
+	s.history = kept
+	return s.save()
+}
+
+// ListSaved 返回用户可见的保存的查询：自己创建的和共享的，按名称排序
+func (s *Store) ListSaved(user, dbName, collectionName string) []*SavedQuery {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	queries := []*SavedQuery{}
+	for _, query := range s.saved {
+		if query.Owner != user && !query.Shared {
+			continue
+		}
+		if !matches(query.Database, query.Collection, dbName, collectionName) {
+			continue
+		}
+		copied := *query
+		queries = append(queries, &copied)
+	}
+	sort.Slice(queries, func(i, k int) bool { return queries[i].Name < queries[k].Name })
+	return queries
+}
+
+// GetSaved 获取用户可见的保存的查询
+func (s *Store) GetSaved(user, id string) (*SavedQuery, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	query, exists := s.saved[id]
+	if !exists || (query.Owner != user && !query.Shared) {
+		return nil, ErrNotFound
+	}
+	copied := *query
+	return &copied, nil
+}
+
+// AddSaved 保存查询，创建者为user
+func (s *Store) AddSaved(user string, query *SavedQuery) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now().Unix()
+	query.ID = s.nextID("q")
+	query.Owner = user
+	query.CreatedAt = now
+	query.UpdatedAt = now
+
+	s.saved[query.ID] = query
+	return s.save()
+}
+
+// UpdateSaved 更新保存的查询，只有创建者可以修改
+func (s *Store) UpdateSaved(user, id string, query *SavedQuery) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	existing, err := s.owned(user, id)
+	if err != nil {
+		return err
+	}
+
+	query.ID = id
+	query.Owner = existing.Owner
+	query.CreatedAt = existing.CreatedAt
+	query.UpdatedAt = time.Now().Unix()
+
+	s.saved[id] = query
+	return s.save()
+}
+
+// DeleteSaved 删除保存的查询，只有创建者可以删除
+func (s *Store) DeleteSaved(user, id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, err := s.owned(user, id); err != nil {
+		return err
+	}
+	delete(s.saved, id)
+	return s.save()
+}
+
+// owned 获取用户创建的查询，调用方需持有锁
+func (s *Store) owned(user, id string) (*SavedQuery, error) {
+	query, exists := s.saved[id]
+	if !exists || (query.Owner != user && !query.Shared) {
+		return nil, ErrNotFound
+	}
+	if query.Owner != user {
+		return nil, ErrForbidden
+	}
+	return query, nil
+}
+
+// matches 按数据库和集合过滤，过滤条件为空表示不限
+func matches(entryDB, entryCollection, dbName, collectionName string) bool {
+	return (dbName == "" || entryDB == dbName) && (collectionName == "" || entryCollection == collectionName)
+}
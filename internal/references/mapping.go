@@ -0,0 +1,15 @@
+package references
+
+// Mapping 字段到被引用集合的映射：Database.Collection 中 Field 字段的值为 TargetDatabase.TargetCollection 中文档的 _id。
+// Field 为不含数组下标的点分隔路径，如 orderIds、items.productId
+type Mapping struct {
+	ID               string `json:"id"`
+	Connection       string `json:"connection,omitempty"`
+	Database         string `json:"database"`
+	Collection       string `json:"collection"`
+	Field            string `json:"field"`
+	TargetDatabase   string `json:"targetDatabase"`
+	TargetCollection string `json:"targetCollection"`
+	CreatedBy        string `json:"createdBy"`
+	CreatedAt        int64  `json:"createdAt"`
+}
@@ -0,0 +1,138 @@
+package references
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrNotFound 引用映射不存在
+var ErrNotFound = errors.New("reference mapping not found")
+
+// Store 引用映射，持久化在JSON文件中
+type Store struct {
+	mappings map[string]*Mapping
+	mutex    sync.RWMutex
+	filePath string
+	seq      int64
+}
+
+// NewStore 创建引用映射存储
+func NewStore(filePath string) *Store {
+	return &Store{
+		mappings: make(map[string]*Mapping),
+		filePath: filePath,
+	}
+}
+
+// Load 加载引用映射
+func (s *Store) Load() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	data, err := os.ReadFile(s.filePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var mappings []*Mapping
+	if err := json.Unmarshal(data, &mappings); err != nil {
+		return err
+	}
+	for _, mapping := range mappings {
+		s.mappings[mapping.ID] = mapping
+	}
+	return nil
+}
+
+// save 保存到文件，调用方需持有锁
+func (s *Store) save() error {
+	data, err := json.MarshalIndent(s.sorted(func(*Mapping) bool { return true }), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.filePath, data, 0644)
+}
+
+// sorted 按数据库、集合、字段排序返回符合条件的映射副本，调用方需持有锁
+func (s *Store) sorted(keep func(*Mapping) bool) []*Mapping {
+	mappings := []*Mapping{}
+	for _, mapping := range s.mappings {
+		if keep(mapping) {
+			copied := *mapping
+			mappings = append(mappings, &copied)
+		}
+	}
+	sort.Slice(mappings, func(i, k int) bool {
+		a, b := mappings[i], mappings[k]
+		if a.Database != b.Database {
+			return a.Database < b.Database
+		}
+		if a.Collection != b.Collection {
+			return a.Collection < b.Collection
+		}
+		return a.Field < b.Field
+	})
+	return mappings
+}
+
+// List 返回连接下的引用映射，dbName、collectionName不为空时只返回该集合中字段的映射
+func (s *Store) List(connection, dbName, collectionName string) []*Mapping {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.sorted(func(mapping *Mapping) bool {
+		return mapping.Connection == connection &&
+			(dbName == "" || mapping.Database == dbName) &&
+			(collectionName == "" || mapping.Collection == collectionName)
+	})
+}
+
+// Targeting 返回连接下指向某个集合的引用映射，用于查找引用了某文档的文档
+func (s *Store) Targeting(connection, dbName, collectionName string) []*Mapping {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.sorted(func(mapping *Mapping) bool {
+		return mapping.Connection == connection && mapping.TargetDatabase == dbName && mapping.TargetCollection == collectionName
+	})
+}
+
+// Add 添加引用映射，同一集合的同一字段已有映射时替换原来的映射
+func (s *Store) Add(user string, mapping *Mapping) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for id, existing := range s.mappings {
+		if existing.Connection == mapping.Connection && existing.Database == mapping.Database &&
+			existing.Collection == mapping.Collection && existing.Field == mapping.Field {
+			delete(s.mappings, id)
+		}
+	}
+
+	s.seq++
+	mapping.ID = fmt.Sprintf("r_%d_%d", time.Now().UnixNano(), s.seq)
+	mapping.CreatedBy = user
+	mapping.CreatedAt = time.Now().Unix()
+	s.mappings[mapping.ID] = mapping
+	return s.save()
+}
+
+// Delete 删除引用映射
+func (s *Store) Delete(id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.mappings[id]; !exists {
+		return ErrNotFound
+	}
+	delete(s.mappings, id)
+	return s.save()
+}
@@ -0,0 +1,301 @@
+package shell
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dop251/goja"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// setupGlobals 注册全局对象和辅助函数；ObjectId、ISODate等返回Extended JSON形式的对象，可直接用于查询条件
+func (s *Session) setupGlobals() {
+	s.vm.Set("db", s.vm.NewDynamicObject(&dbObject{s: s}))
+
+	s.vm.Set("print", func(call goja.FunctionCall) goja.Value {
+		parts := make([]string, len(call.Arguments))
+		for i, arg := range call.Arguments {
+			parts[i] = s.display(arg)
+		}
+		s.write(strings.Join(parts, " "))
+		return goja.Undefined()
+	})
+	s.vm.Set("printjson", func(value goja.Value) {
+		s.write(s.stringify(value, true))
+	})
+
+	s.vm.Set("ObjectId", func(call goja.FunctionCall) goja.Value {
+		id := primitive.NewObjectID()
+		if arg := call.Argument(0); !goja.IsUndefined(arg) {
+			var err error
+			if id, err = primitive.ObjectIDFromHex(arg.String()); err != nil {
+				panic(s.vm.NewTypeError("invalid ObjectId %q", arg.String()))
+			}
+		}
+		return s.toJS(id)
+	})
+	s.vm.Set("ISODate", func(call goja.FunctionCall) goja.Value {
+		t := time.Now()
+		if arg := call.Argument(0); !goja.IsUndefined(arg) {
+			var err error
+			if t, err = parseDate(arg.String()); err != nil {
+				panic(s.vm.NewTypeError("invalid date %q", arg.String()))
+			}
+		}
+		return s.toJS(primitive.NewDateTimeFromTime(t))
+	})
+	s.vm.Set("NumberLong", func(value goja.Value) goja.Value {
+		return s.extendedValue("$numberLong", value)
+	})
+	s.vm.Set("NumberDecimal", func(value goja.Value) goja.Value {
+		return s.extendedValue("$numberDecimal", value)
+	})
+	s.vm.Set("sleep", func(ms int64) {
+		select {
+		case <-time.After(time.Duration(ms) * time.Millisecond):
+		case <-s.ctx.Done():
+		}
+	})
+}
+
+// extendedValue 构造 {"$numberLong": "..."} 这类只有一个字段的Extended JSON对象
+func (s *Session) extendedValue(key string, value goja.Value) goja.Value {
+	obj := s.vm.NewObject()
+	obj.Set(key, value.String())
+	return obj
+}
+
+// parseDate 解析ISODate的参数，支持带时区的RFC 3339和省略时间或时区的写法（按UTC处理）
+func parseDate(text string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999", "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.Parse(layout, text); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", text)
+}
+
+// dbObject 脚本中的db对象：db.<集合名> 返回集合对象，同时提供 getName、getCollection、getSiblingDB、runCommand 等方法
+type dbObject struct {
+	s    *Session
+	name string // 为空表示会话的当前数据库，随 use 切换
+}
+
+func (d *dbObject) dbName() string {
+	if d.name == "" {
+		return d.s.dbName
+	}
+	return d.name
+}
+
+func (d *dbObject) Get(key string) goja.Value {
+	s := d.s
+	switch key {
+	case "getName", "toString":
+		return s.vm.ToValue(func() string { return d.dbName() })
+	case "getCollection":
+		return s.vm.ToValue(func(name string) goja.Value { return s.collection(d.dbName(), name) })
+	case "getCollectionNames":
+		return s.vm.ToValue(func() goja.Value {
+			names, err := s.service.GetCollections(s.ctx, d.dbName())
+			if err != nil {
+				s.throw(err)
+			}
+			return s.vm.ToValue(names)
+		})
+	case "getSiblingDB":
+		return s.vm.ToValue(func(name string) goja.Value {
+			return s.vm.NewDynamicObject(&dbObject{s: s, name: name})
+		})
+	case "runCommand":
+		return s.vm.ToValue(func(command goja.Value) goja.Value { return s.runCommand(d.dbName(), command) })
+	}
+	return s.collection(d.dbName(), key)
+}
+
+func (d *dbObject) Set(key string, val goja.Value) bool { return false }
+func (d *dbObject) Has(key string) bool                 { return true }
+func (d *dbObject) Delete(key string) bool              { return false }
+func (d *dbObject) Keys() []string                      { return nil }
+
+// runCommand 执行数据库命令，是否允许执行与命令控制台使用相同的规则
+func (s *Session) runCommand(dbName string, value goja.Value) goja.Value {
+	command := s.toDocument(value, "command")
+	if len(command) == 0 {
+		panic(s.vm.NewTypeError("command document must not be empty"))
+	}
+	if s.options.AllowCommand == nil || !s.options.AllowCommand(command[0].Key) {
+		s.throw(fmt.Errorf("command %q is not allowed", command[0].Key))
+	}
+	result, err := s.service.RunCommand(s.ctx, dbName, command)
+	if err != nil {
+		s.throw(err)
+	}
+	return s.toJS(result)
+}
+
+// collection 构造脚本中的集合对象
+func (s *Session) collection(dbName, name string) goja.Value {
+	coll := s.service.Collection(dbName, name)
+	obj := s.vm.NewObject()
+
+	obj.Set("getName", func() string { return name })
+	obj.Set("toString", func() string { return dbName + "." + name })
+
+	obj.Set("find", func(call goja.FunctionCall) goja.Value {
+		opts := options.Find()
+		if projection := s.toDocument(call.Argument(1), "projection"); len(projection) > 0 {
+			opts.SetProjection(projection)
+		}
+		return s.newCursor(&cursor{s: s, collection: coll, filter: s.toDocument(call.Argument(0), "filter"), find: opts})
+	})
+	obj.Set("findOne", func(call goja.FunctionCall) goja.Value {
+		opts := options.FindOne()
+		if projection := s.toDocument(call.Argument(1), "projection"); len(projection) > 0 {
+			opts.SetProjection(projection)
+		}
+		var doc bson.Raw
+		err := coll.FindOne(s.ctx, s.toDocument(call.Argument(0), "filter"), opts).Decode(&doc)
+		if err == mongo.ErrNoDocuments {
+			return goja.Null()
+		}
+		if err != nil {
+			s.throw(err)
+		}
+		return s.toJS(doc)
+	})
+	obj.Set("aggregate", func(call goja.FunctionCall) goja.Value {
+		pipeline, ok := s.toBSON(call.Argument(0)).(bson.A)
+		if !ok {
+			panic(s.vm.NewTypeError("pipeline must be an array of stages"))
+		}
+		return s.newCursor(&cursor{s: s, collection: coll, pipeline: pipeline, aggregate: true})
+	})
+	obj.Set("countDocuments", func(call goja.FunctionCall) goja.Value {
+		count, err := coll.CountDocuments(s.ctx, s.toDocument(call.Argument(0), "filter"))
+		if err != nil {
+			s.throw(err)
+		}
+		return s.vm.ToValue(count)
+	})
+	obj.Set("distinct", func(call goja.FunctionCall) goja.Value {
+		values, err := coll.Distinct(s.ctx, call.Argument(0).String(), s.toDocument(call.Argument(1), "filter"))
+		if err != nil {
+			s.throw(err)
+		}
+		return s.toJS(bson.A(values))
+	})
+
+	obj.Set("insertOne", func(call goja.FunctionCall) goja.Value {
+		result, err := coll.InsertOne(s.ctx, s.toDocument(call.Argument(0), "document"))
+		if err != nil {
+			s.throw(err)
+		}
+		return s.toJS(bson.D{{Key: "acknowledged", Value: true}, {Key: "insertedId", Value: result.InsertedID}})
+	})
+	obj.Set("insertMany", func(call goja.FunctionCall) goja.Value {
+		docs, ok := s.toBSON(call.Argument(0)).(bson.A)
+		if !ok {
+			panic(s.vm.NewTypeError("documents must be an array"))
+		}
+		result, err := coll.InsertMany(s.ctx, docs)
+		if err != nil {
+			s.throw(err)
+		}
+		return s.toJS(bson.D{{Key: "acknowledged", Value: true}, {Key: "insertedIds", Value: bson.A(result.InsertedIDs)}})
+	})
+
+	update := func(many bool) func(goja.FunctionCall) goja.Value {
+		return func(call goja.FunctionCall) goja.Value {
+			filter := s.toDocument(call.Argument(0), "filter")
+			change := s.toBSON(call.Argument(1))
+			switch change.(type) {
+			case bson.D, bson.A:
+			default:
+				panic(s.vm.NewTypeError("update must be a document or a pipeline"))
+			}
+			opts := updateOptions(s.toDocument(call.Argument(2), "options"))
+
+			var result *mongo.UpdateResult
+			var err error
+			if many {
+				result, err = coll.UpdateMany(s.ctx, filter, change, opts)
+			} else {
+				result, err = coll.UpdateOne(s.ctx, filter, change, opts)
+			}
+			if err != nil {
+				s.throw(err)
+			}
+			return s.updateResult(result)
+		}
+	}
+	obj.Set("updateOne", update(false))
+	obj.Set("updateMany", update(true))
+	obj.Set("replaceOne", func(call goja.FunctionCall) goja.Value {
+		opts := options.Replace()
+		if upsert, ok := s.toDocument(call.Argument(2), "options").Map()["upsert"].(bool); ok {
+			opts.SetUpsert(upsert)
+		}
+		result, err := coll.ReplaceOne(s.ctx, s.toDocument(call.Argument(0), "filter"), s.toDocument(call.Argument(1), "replacement"), opts)
+		if err != nil {
+			s.throw(err)
+		}
+		return s.updateResult(result)
+	})
+
+	remove := func(many bool) func(goja.FunctionCall) goja.Value {
+		return func(call goja.FunctionCall) goja.Value {
+			filter := s.toDocument(call.Argument(0), "filter")
+			var result *mongo.DeleteResult
+			var err error
+			if many {
+				result, err = coll.DeleteMany(s.ctx, filter)
+			} else {
+				result, err = coll.DeleteOne(s.ctx, filter)
+			}
+			if err != nil {
+				s.throw(err)
+			}
+			return s.toJS(bson.D{{Key: "acknowledged", Value: true}, {Key: "deletedCount", Value: result.DeletedCount}})
+		}
+	}
+	obj.Set("deleteOne", remove(false))
+	obj.Set("deleteMany", remove(true))
+
+	return obj
+}
+
+// updateOptions 解析 updateOne/updateMany 的 upsert 和 arrayFilters 选项
+func updateOptions(doc bson.D) *options.UpdateOptions {
+	opts := options.Update()
+	for _, e := range doc {
+		switch e.Key {
+		case "upsert":
+			if upsert, ok := e.Value.(bool); ok {
+				opts.SetUpsert(upsert)
+			}
+		case "arrayFilters":
+			if filters, ok := e.Value.(bson.A); ok {
+				opts.SetArrayFilters(options.ArrayFilters{Filters: filters})
+			}
+		}
+	}
+	return opts
+}
+
+func (s *Session) updateResult(result *mongo.UpdateResult) goja.Value {
+	doc := bson.D{
+		{Key: "acknowledged", Value: true},
+		{Key: "matchedCount", Value: result.MatchedCount},
+		{Key: "modifiedCount", Value: result.ModifiedCount},
+		{Key: "upsertedCount", Value: result.UpsertedCount},
+	}
+	if result.UpsertedID != nil {
+		doc = append(doc, bson.E{Key: "upsertedId", Value: result.UpsertedID})
+	}
+	return s.toJS(doc)
+}
@@ -0,0 +1,198 @@
+package shell
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/dop251/goja"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// cursorKey 游标对象上指向Go游标的隐藏属性
+const cursorKey = "__cursor"
+
+// cursor find/aggregate返回的游标，第一次读取时才执行查询；执行结束后自动关闭
+type cursor struct {
+	s          *Session
+	collection *mongo.Collection
+
+	filter bson.D
+	find   *options.FindOptions
+
+	aggregate bool
+	pipeline  bson.A
+
+	results  *mongo.Cursor
+	buffered bson.Raw
+	closed   bool
+}
+
+// asCursor 取出JS值对应的游标，不是游标时返回nil
+func asCursor(value goja.Value) *cursor {
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		return nil
+	}
+	hidden := obj.Get(cursorKey)
+	if hidden == nil {
+		return nil
+	}
+	c, _ := hidden.Export().(*cursor)
+	return c
+}
+
+// newCursor 构造脚本中的游标对象，sort、limit、skip、projection可链式调用
+func (s *Session) newCursor(c *cursor) goja.Value {
+	obj := s.vm.NewObject()
+	obj.DefineDataProperty(cursorKey, s.vm.ToValue(c), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE)
+
+	modifier := func(apply func(value goja.Value)) func(goja.FunctionCall) goja.Value {
+		return func(call goja.FunctionCall) goja.Value {
+			if c.results != nil || c.closed {
+				panic(s.vm.NewTypeError("cannot modify a cursor after it has been iterated"))
+			}
+			apply(call.Argument(0))
+			return obj
+		}
+	}
+	obj.Set("sort", modifier(func(value goja.Value) {
+		sort := s.toDocument(value, "sort")
+		if c.aggregate {
+			c.pipeline = append(c.pipeline, bson.D{{Key: "$sort", Value: sort}})
+		} else {
+			c.find.SetSort(sort)
+		}
+	}))
+	obj.Set("skip", modifier(func(value goja.Value) {
+		if c.aggregate {
+			c.pipeline = append(c.pipeline, bson.D{{Key: "$skip", Value: value.ToInteger()}})
+		} else {
+			c.find.SetSkip(value.ToInteger())
+		}
+	}))
+	obj.Set("limit", modifier(func(value goja.Value) {
+		if c.aggregate {
+			c.pipeline = append(c.pipeline, bson.D{{Key: "$limit", Value: value.ToInteger()}})
+		} else {
+			c.find.SetLimit(value.ToInteger())
+		}
+	}))
+	obj.Set("projection", modifier(func(value goja.Value) {
+		projection := s.toDocument(value, "projection")
+		if c.aggregate {
+			c.pipeline = append(c.pipeline, bson.D{{Key: "$project", Value: projection}})
+		} else {
+			c.find.SetProjection(projection)
+		}
+	}))
+
+	obj.Set("hasNext", func() bool {
+		return c.hasNext()
+	})
+	obj.Set("next", func() goja.Value {
+		if !c.hasNext() {
+			s.throw(errors.New("cursor is exhausted"))
+		}
+		return c.take()
+	})
+	obj.Set("toArray", func() goja.Value {
+		docs := []interface{}{}
+		for c.hasNext() {
+			docs = append(docs, c.take())
+		}
+		return s.vm.NewArray(docs...)
+	})
+	obj.Set("forEach", func(call goja.FunctionCall) goja.Value {
+		fn, ok := goja.AssertFunction(call.Argument(0))
+		if !ok {
+			panic(s.vm.NewTypeError("forEach requires a function"))
+		}
+		for c.hasNext() {
+			if _, err := fn(goja.Undefined(), c.take()); err != nil {
+				panic(err)
+			}
+		}
+		return goja.Undefined()
+	})
+	obj.Set("count", func() int64 {
+		if c.aggregate {
+			panic(s.vm.NewTypeError("count is not supported on aggregation cursors"))
+		}
+		count, err := c.collection.CountDocuments(s.ctx, c.filter)
+		if err != nil {
+			s.throw(err)
+		}
+		return count
+	})
+	obj.Set("close", func() {
+		c.close()
+	})
+	return obj
+}
+
+// open 执行查询，同一次执行中打开的游标在执行结束时统一关闭
+func (c *cursor) open() {
+	if c.closed {
+		c.s.throw(errors.New("cursor has been closed"))
+	}
+	if c.results != nil {
+		return
+	}
+
+	var err error
+	if c.aggregate {
+		c.results, err = c.collection.Aggregate(c.s.ctx, c.pipeline)
+	} else {
+		c.results, err = c.collection.Find(c.s.ctx, c.filter, c.find)
+	}
+	if err != nil {
+		c.s.throw(err)
+	}
+	c.s.cursors = append(c.s.cursors, c)
+}
+
+func (c *cursor) hasNext() bool {
+	c.open()
+	if c.buffered != nil {
+		return true
+	}
+	if c.results.Next(c.s.ctx) {
+		c.buffered = append(bson.Raw(nil), c.results.Current...)
+		return true
+	}
+	if err := c.results.Err(); err != nil {
+		c.s.throw(err)
+	}
+	return false
+}
+
+// take 取出已缓冲的下一个文档，调用前需确认hasNext为true
+func (c *cursor) take() goja.Value {
+	doc := c.buffered
+	c.buffered = nil
+	return c.s.toJS(doc)
+}
+
+// displayBatch 显示游标的前一批文档，类似mongosh直接输入查询时的输出
+func (c *cursor) displayBatch() string {
+	var docs []string
+	for len(docs) < displayBatchSize && c.hasNext() {
+		docs = append(docs, c.s.stringify(c.take(), true))
+	}
+	if len(docs) == 0 {
+		return "(no documents)"
+	}
+	if c.hasNext() {
+		docs = append(docs, "... more documents, use toArray() or forEach() to iterate all of them")
+	}
+	return strings.Join(docs, "\n")
+}
+
+func (c *cursor) close() {
+	if c.results != nil && !c.closed {
+		c.results.Close(c.s.ctx)
+	}
+	c.closed = true
+}
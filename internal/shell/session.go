@@ -0,0 +1,297 @@
+package shell
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"m-db-ui/internal/database"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// maxOutputBytes 单次执行最多输出的字节数，超出后中断脚本
+const maxOutputBytes = 1 << 20
+
+// displayBatchSize 表达式结果为游标时显示的文档数
+const displayBatchSize = 20
+
+var (
+	// ErrCanceled 脚本被用户取消
+	ErrCanceled = errors.New("execution canceled")
+	// ErrOutputLimit 脚本输出超过上限
+	ErrOutputLimit = fmt.Errorf("output exceeds %d bytes", maxOutputBytes)
+
+	useCommand  = regexp.MustCompile(`^use\s+([^\s;]+);?$`)
+	showCommand = regexp.MustCompile(`^show\s+([^\s;]+);?$`)
+)
+
+// Options 脚本会话参数
+type Options struct {
+	Database     string                 // 初始数据库
+	Timeout      time.Duration          // 单次执行的超时，0表示不限制
+	AllowCommand func(name string) bool // db.runCommand 能否执行指定命令，为nil时不允许执行任何命令
+}
+
+// Session 一个脚本会话，变量在多次执行之间保留；同一时间只能执行一段脚本
+type Session struct {
+	service *database.Service
+	options Options
+	vm      *goja.Runtime
+	dbName  string
+
+	mutex  sync.Mutex
+	cancel context.CancelFunc
+
+	// 以下字段只在执行期间使用
+	ctx         context.Context
+	output      func(text string)
+	outputBytes int
+	cursors     []*cursor
+}
+
+// NewSession 创建脚本会话，全局提供 db、print、printjson、ObjectId、ISODate 等对象和函数
+func NewSession(service *database.Service, options Options) *Session {
+	if options.Database == "" {
+		options.Database = "test"
+	}
+	s := &Session{
+		service: service,
+		options: options,
+		vm:      goja.New(),
+		dbName:  options.Database,
+	}
+	s.setupGlobals()
+	return s
+}
+
+// Database 当前数据库
+func (s *Session) Database() string {
+	return s.dbName
+}
+
+// Execute 执行一段脚本，print等输出通过output实时返回；返回最后一个表达式的显示文本。
+// 支持 use <db>、show dbs、show collections 几个shell命令
+func (s *Session) Execute(ctx context.Context, code string, output func(text string)) (string, error) {
+	if s.options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.options.Timeout)
+		defer cancel()
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	s.mutex.Lock()
+	s.cancel = func() { cancel(ErrCanceled) }
+	s.mutex.Unlock()
+	defer func() {
+		s.mutex.Lock()
+		s.cancel = nil
+		s.mutex.Unlock()
+	}()
+
+	code = strings.TrimSpace(code)
+	if result, handled, err := s.shellCommand(ctx, code); handled {
+		return result, err
+	}
+
+	s.ctx = ctx
+	s.output = output
+	s.outputBytes = 0
+	s.vm.ClearInterrupt()
+	defer s.closeCursors()
+
+	// 超时或取消时中断脚本，数据库操作也使用同一个上下文，会被一并取消
+	stop := context.AfterFunc(ctx, func() { s.vm.Interrupt(context.Cause(ctx)) })
+	defer stop()
+
+	value, err := s.vm.RunString(code)
+	if err == nil {
+		var display string
+		display, err = s.catch(func() string { return s.display(value) })
+		if err == nil {
+			return display, nil
+		}
+	}
+	return "", s.scriptError(ctx, err)
+}
+
+// Cancel 中断正在执行的脚本
+func (s *Session) Cancel() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.cancel != nil {
+		s.cancel()
+	}
+}
+
+// scriptError 将goja的错误转换为易读的错误
+func (s *Session) scriptError(ctx context.Context, err error) error {
+	var interrupted *goja.InterruptedError
+	if errors.As(err, &interrupted) {
+		if cause, ok := interrupted.Value().(error); ok {
+			err = cause
+		}
+	}
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return fmt.Errorf("execution timed out after %s", s.options.Timeout)
+	case errors.Is(err, ErrCanceled), errors.Is(err, ErrOutputLimit):
+		return err
+	case ctx.Err() != nil:
+		return context.Cause(ctx)
+	}
+
+	var exception *goja.Exception
+	if errors.As(err, &exception) {
+		// 数据库操作等Go代码返回的错误保留原始信息，脚本抛出的异常使用其文本
+		if cause := exception.Unwrap(); cause != nil {
+			return cause
+		}
+		return errors.New(exception.Value().String())
+	}
+	return err
+}
+
+// catch 在脚本之外调用可能抛出JS异常的函数时，将异常转换为错误
+func (s *Session) catch(fn func() string) (result string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			switch x := r.(type) {
+			case goja.Value:
+				err = errors.New(x.String())
+			case error:
+				err = x
+			default:
+				panic(r)
+			}
+		}
+	}()
+	return fn(), nil
+}
+
+// shellCommand 处理 use、show 等非JavaScript的shell命令
+func (s *Session) shellCommand(ctx context.Context, code string) (string, bool, error) {
+	if match := useCommand.FindStringSubmatch(code); match != nil {
+		s.dbName = match[1]
+		return "switched to db " + s.dbName, true, nil
+	}
+
+	match := showCommand.FindStringSubmatch(code)
+	if match == nil {
+		return "", false, nil
+	}
+	var names []string
+	var err error
+	switch match[1] {
+	case "dbs", "databases":
+		names, err = s.service.GetDatabases(ctx)
+	case "collections", "tables":
+		names, err = s.service.GetCollections(ctx, s.dbName)
+	default:
+		return "", true, fmt.Errorf("unknown show target %q", match[1])
+	}
+	if err != nil {
+		return "", true, err
+	}
+	return strings.Join(names, "\n"), true, nil
+}
+
+// write 输出一行文本，超过输出上限时中断脚本
+func (s *Session) write(text string) {
+	s.outputBytes += len(text) + 1
+	if s.outputBytes > maxOutputBytes {
+		s.vm.Interrupt(ErrOutputLimit)
+		return
+	}
+	if s.output != nil {
+		s.output(text)
+	}
+}
+
+// display 将值格式化为显示文本：游标显示前一批文档，对象和数组格式化为JSON，其他值转换为字符串
+func (s *Session) display(value goja.Value) string {
+	if value == nil || goja.IsUndefined(value) {
+		return ""
+	}
+	if c := asCursor(value); c != nil {
+		return c.displayBatch()
+	}
+	if object, ok := value.(*goja.Object); ok {
+		if _, isFunction := goja.AssertFunction(object); !isFunction {
+			return s.stringify(object, true)
+		}
+	}
+	return value.String()
+}
+
+// stringify 使用JSON.stringify格式化值，对象中的ObjectId、日期等保持Extended JSON形式
+func (s *Session) stringify(value goja.Value, indent bool) string {
+	stringify, _ := goja.AssertFunction(s.vm.Get("JSON").ToObject(s.vm).Get("stringify"))
+	args := []goja.Value{value}
+	if indent {
+		args = append(args, goja.Null(), s.vm.ToValue(2))
+	}
+	result, err := stringify(goja.Undefined(), args...)
+	if err != nil {
+		panic(err)
+	}
+	if goja.IsUndefined(result) {
+		return value.String()
+	}
+	return result.String()
+}
+
+// toBSON 将JS值转换为BSON，先通过JSON.stringify得到Extended JSON再解析，保留字段顺序和$oid、$date等类型
+func (s *Session) toBSON(value goja.Value) interface{} {
+	if value == nil || goja.IsUndefined(value) || goja.IsNull(value) {
+		return nil
+	}
+	var wrapper bson.Raw
+	text := `{"v":` + s.stringify(value, false) + `}`
+	if err := bson.UnmarshalExtJSON([]byte(text), false, &wrapper); err != nil {
+		panic(s.vm.NewTypeError("cannot convert value to BSON: %v", err))
+	}
+	var result interface{}
+	if err := wrapper.Lookup("v").Unmarshal(&result); err != nil {
+		panic(s.vm.NewGoError(err))
+	}
+	return result
+}
+
+// toDocument 将JS值转换为BSON文档，未指定时返回空文档
+func (s *Session) toDocument(value goja.Value, what string) bson.D {
+	switch v := s.toBSON(value).(type) {
+	case nil:
+		return bson.D{}
+	case bson.D:
+		return v
+	}
+	panic(s.vm.NewTypeError("%s must be a document", what))
+}
+
+// toJS 将BSON值转换为JS值，ObjectId、日期等以Extended JSON形式表示
+func (s *Session) toJS(value interface{}) goja.Value {
+	parse, _ := goja.AssertFunction(s.vm.Get("JSON").ToObject(s.vm).Get("parse"))
+	result, err := parse(goja.Undefined(), s.vm.ToValue(string(database.ExtJSONValue(value))))
+	if err != nil {
+		panic(err)
+	}
+	return result
+}
+
+// throw 将Go错误作为JS异常抛出
+func (s *Session) throw(err error) {
+	panic(s.vm.NewGoError(err))
+}
+
+func (s *Session) closeCursors() {
+	for _, c := range s.cursors {
+		c.close()
+	}
+	s.cursors = nil
+}
@@ -0,0 +1,149 @@
+package sqlquery
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// tokenKind 词法单元类型
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenSymbol
+)
+
+// token 词法单元；关键字也作为标识符返回，由语法分析按名称识别
+type token struct {
+	kind   tokenKind
+	text   string
+	quoted bool // 用反引号括起的标识符，不会被识别为关键字
+	pos    int
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of input"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// tokenize 将SQL文本切分为词法单元。
+// 标识符可以包含点号表示嵌套字段（如 address.city），也可以用反引号括起；字符串使用单引号或双引号，引号重复两次表示引号本身
+func tokenize(sql string) ([]token, error) {
+	var tokens []token
+	runes := []rune(sql)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			// 行注释
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case isIdentStart(r):
+			start := i
+			for i < len(runes) && (isIdentPart(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: string(runes[start:i]), pos: start})
+		case r == '`':
+			start := i
+			text, next, err := readQuoted(runes, i)
+			if err != nil {
+				return nil, err
+			}
+			i = next
+			// 反引号标识符后可以继续用点号连接字段，如 `order items`.qty
+			for i < len(runes) && runes[i] == '.' {
+				i++
+				if i < len(runes) && runes[i] == '`' {
+					part, after, err := readQuoted(runes, i)
+					if err != nil {
+						return nil, err
+					}
+					text += "." + part
+					i = after
+					continue
+				}
+				partStart := i
+				for i < len(runes) && isIdentPart(runes[i]) {
+					i++
+				}
+				text += "." + string(runes[partStart:i])
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: text, quoted: true, pos: start})
+		case r == '\'' || r == '"':
+			start := i
+			text, next, err := readQuoted(runes, i)
+			if err != nil {
+				return nil, err
+			}
+			i = next
+			tokens = append(tokens, token{kind: tokenString, text: text, pos: start})
+		case unicode.IsDigit(r) || (r == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			if i < len(runes) && (runes[i] == 'e' || runes[i] == 'E') {
+				i++
+				if i < len(runes) && (runes[i] == '+' || runes[i] == '-') {
+					i++
+				}
+				for i < len(runes) && unicode.IsDigit(runes[i]) {
+					i++
+				}
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: string(runes[start:i]), pos: start})
+		default:
+			start := i
+			symbol := string(r)
+			if i+1 < len(runes) {
+				switch two := string(runes[i : i+2]); two {
+				case "<=", ">=", "<>", "!=":
+					symbol = two
+				}
+			}
+			if !strings.Contains("=<>!(),*;-+", string(r)) || symbol == "!" {
+				return nil, fmt.Errorf("unexpected character %q at position %d", r, start+1)
+			}
+			i += len([]rune(symbol))
+			tokens = append(tokens, token{kind: tokenSymbol, text: symbol, pos: start})
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(runes)}), nil
+}
+
+// readQuoted 读取引号括起的文本，返回内容和结束引号之后的位置
+func readQuoted(runes []rune, start int) (string, int, error) {
+	quote := runes[start]
+	var b strings.Builder
+	for i := start + 1; i < len(runes); i++ {
+		if runes[i] != quote {
+			b.WriteRune(runes[i])
+			continue
+		}
+		if i+1 < len(runes) && runes[i+1] == quote {
+			b.WriteRune(quote)
+			i++
+			continue
+		}
+		return b.String(), i + 1, nil
+	}
+	return "", 0, fmt.Errorf("unterminated %c at position %d", quote, start+1)
+}
+
+func isIdentStart(r rune) bool {
+	return r == '_' || r == '$' || unicode.IsLetter(r)
+}
+
+func isIdentPart(r rune) bool {
+	return isIdentStart(r) || unicode.IsDigit(r)
+}
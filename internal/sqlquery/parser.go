@@ -0,0 +1,668 @@
+package sqlquery
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Statement 解析后的SELECT语句
+type Statement struct {
+	Columns []SelectItem // 为空表示 SELECT *
+	Table   TableRef
+	Joins   []Join
+	Where   Expr
+	GroupBy []string
+	Having  Expr
+	OrderBy []OrderItem
+	Limit   int64 // -1表示未指定
+	Offset  int64
+}
+
+// TableRef FROM或JOIN中的集合及其别名
+type TableRef struct {
+	Name  string
+	Alias string
+}
+
+// Ref 在字段引用中使用的名称，有别名时为别名
+func (t TableRef) Ref() string {
+	if t.Alias != "" {
+		return t.Alias
+	}
+	return t.Name
+}
+
+// Join 等值连接：ON Left = Right
+type Join struct {
+	Table TableRef
+	Outer bool // LEFT JOIN，没有匹配的文档时保留左侧文档
+	Left  string
+	Right string
+}
+
+// Operand 字段或聚合函数，Func为空表示字段；COUNT(*) 的Column为 "*"
+type Operand struct {
+	Column string
+	Func   string
+}
+
+func (o Operand) String() string {
+	if o.Func == "" {
+		return o.Column
+	}
+	return o.Func + "(" + o.Column + ")"
+}
+
+// SelectItem 查询的列
+type SelectItem struct {
+	Operand
+	Alias string
+}
+
+// OrderItem 排序字段
+type OrderItem struct {
+	Operand
+	Desc bool
+}
+
+// Expr WHERE和HAVING中的条件表达式
+type Expr interface {
+	expr()
+}
+
+// Logical AND或OR连接的多个条件
+type Logical struct {
+	Op    string // "AND" 或 "OR"
+	Terms []Expr
+}
+
+// Not 取反的条件
+type Not struct {
+	Expr Expr
+}
+
+// Comparison 字段与值的比较，Op为 = != < <= > >=
+type Comparison struct {
+	Left  Operand
+	Op    string
+	Value interface{}
+}
+
+// In 字段是否在值列表中
+type In struct {
+	Left   Operand
+	Values []interface{}
+	Not    bool
+}
+
+// Like 按通配符匹配字符串，% 匹配任意个字符，_ 匹配一个字符
+type Like struct {
+	Left       Operand
+	Pattern    string
+	IgnoreCase bool // ILIKE
+	Not        bool
+}
+
+// Between 字段在闭区间内
+type Between struct {
+	Left      Operand
+	Low, High interface{}
+	Not       bool
+}
+
+// IsNull 字段为null或不存在
+type IsNull struct {
+	Left Operand
+	Not  bool
+}
+
+func (*Logical) expr()    {}
+func (*Not) expr()        {}
+func (*Comparison) expr() {}
+func (*In) expr()         {}
+func (*Like) expr()       {}
+func (*Between) expr()    {}
+func (*IsNull) expr()     {}
+
+// aggregateFuncs 支持的聚合函数
+var aggregateFuncs = map[string]bool{"COUNT": true, "SUM": true, "AVG": true, "MIN": true, "MAX": true}
+
+// reserved 不能作为别名使用的关键字
+var reserved = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "GROUP": true, "BY": true, "HAVING": true, "ORDER": true,
+	"LIMIT": true, "OFFSET": true, "JOIN": true, "INNER": true, "LEFT": true, "OUTER": true, "ON": true,
+	"AND": true, "OR": true, "NOT": true, "AS": true, "ASC": true, "DESC": true,
+}
+
+// parser 递归下降语法分析器
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// Parse 解析一条SELECT语句
+func Parse(sql string) (*Statement, error) {
+	tokens, err := tokenize(sql)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	stmt, err := p.statement()
+	if err != nil {
+		return nil, err
+	}
+	p.acceptSymbol(";")
+	if p.peek().kind != tokenEOF {
+		return nil, p.unexpected()
+	}
+	return stmt, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// isKeyword 下一个词法单元是否为指定关键字（不区分大小写）
+func (p *parser) isKeyword(words ...string) bool {
+	for i, word := range words {
+		if p.pos+i >= len(p.tokens) {
+			return false
+		}
+		t := p.tokens[p.pos+i]
+		if t.kind != tokenIdent || t.quoted || !strings.EqualFold(t.text, word) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *parser) acceptKeyword(words ...string) bool {
+	if !p.isKeyword(words...) {
+		return false
+	}
+	p.pos += len(words)
+	return true
+}
+
+func (p *parser) expectKeyword(words ...string) error {
+	if !p.acceptKeyword(words...) {
+		return fmt.Errorf("expected %s but found %s", strings.Join(words, " "), p.peek())
+	}
+	return nil
+}
+
+func (p *parser) acceptSymbol(symbol string) bool {
+	if t := p.peek(); t.kind == tokenSymbol && t.text == symbol {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expectSymbol(symbol string) error {
+	if !p.acceptSymbol(symbol) {
+		return fmt.Errorf("expected %q but found %s", symbol, p.peek())
+	}
+	return nil
+}
+
+func (p *parser) unexpected() error {
+	t := p.peek()
+	if t.kind == tokenEOF {
+		return fmt.Errorf("unexpected end of input")
+	}
+	return fmt.Errorf("unexpected %s at position %d", t, t.pos+1)
+}
+
+// identifier 读取一个不是保留字的标识符
+func (p *parser) identifier(what string) (string, error) {
+	t := p.peek()
+	if t.kind != tokenIdent || (!t.quoted && reserved[strings.ToUpper(t.text)]) {
+		return "", fmt.Errorf("expected %s but found %s", what, t)
+	}
+	p.pos++
+	return t.text, nil
+}
+
+func (p *parser) statement() (*Statement, error) {
+	if err := p.expectKeyword("SELECT"); err != nil {
+		return nil, err
+	}
+	if p.isKeyword("DISTINCT") {
+		return nil, fmt.Errorf("SELECT DISTINCT is not supported, use GROUP BY instead")
+	}
+
+	stmt := &Statement{Limit: -1}
+	if !p.acceptSymbol("*") {
+		for {
+			item, err := p.selectItem()
+			if err != nil {
+				return nil, err
+			}
+			stmt.Columns = append(stmt.Columns, item)
+			if !p.acceptSymbol(",") {
+				break
+			}
+		}
+	}
+
+	if err := p.expectKeyword("FROM"); err != nil {
+		return nil, err
+	}
+	table, err := p.tableRef()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Table = table
+
+	for p.isKeyword("JOIN") || p.isKeyword("INNER") || p.isKeyword("LEFT") {
+		outer := p.acceptKeyword("LEFT")
+		if outer {
+			p.acceptKeyword("OUTER")
+		} else {
+			p.acceptKeyword("INNER")
+		}
+		if err := p.expectKeyword("JOIN"); err != nil {
+			return nil, err
+		}
+		join, err := p.join(outer)
+		if err != nil {
+			return nil, err
+		}
+		stmt.Joins = append(stmt.Joins, join)
+	}
+
+	if p.acceptKeyword("WHERE") {
+		if stmt.Where, err = p.expression(); err != nil {
+			return nil, err
+		}
+	}
+	if p.acceptKeyword("GROUP") {
+		if err := p.expectKeyword("BY"); err != nil {
+			return nil, err
+		}
+		for {
+			column, err := p.identifier("column")
+			if err != nil {
+				return nil, err
+			}
+			stmt.GroupBy = append(stmt.GroupBy, column)
+			if !p.acceptSymbol(",") {
+				break
+			}
+		}
+	}
+	if p.acceptKeyword("HAVING") {
+		if stmt.Having, err = p.expression(); err != nil {
+			return nil, err
+		}
+	}
+	if p.acceptKeyword("ORDER") {
+		if err := p.expectKeyword("BY"); err != nil {
+			return nil, err
+		}
+		for {
+			operand, err := p.operand()
+			if err != nil {
+				return nil, err
+			}
+			item := OrderItem{Operand: operand}
+			if p.acceptKeyword("DESC") {
+				item.Desc = true
+			} else {
+				p.acceptKeyword("ASC")
+			}
+			stmt.OrderBy = append(stmt.OrderBy, item)
+			if !p.acceptSymbol(",") {
+				break
+			}
+		}
+	}
+	if p.acceptKeyword("LIMIT") {
+		limit, err := p.count("LIMIT")
+		if err != nil {
+			return nil, err
+		}
+		stmt.Limit = limit
+		// MySQL写法 LIMIT offset, count
+		if p.acceptSymbol(",") {
+			if stmt.Limit, err = p.count("LIMIT"); err != nil {
+				return nil, err
+			}
+			stmt.Offset = limit
+		}
+	}
+	if p.acceptKeyword("OFFSET") {
+		if stmt.Offset, err = p.count("OFFSET"); err != nil {
+			return nil, err
+		}
+	}
+	return stmt, nil
+}
+
+func (p *parser) selectItem() (SelectItem, error) {
+	operand, err := p.operand()
+	if err != nil {
+		return SelectItem{}, err
+	}
+	item := SelectItem{Operand: operand}
+	if p.acceptKeyword("AS") {
+		if item.Alias, err = p.identifier("alias"); err != nil {
+			return SelectItem{}, err
+		}
+	} else if t := p.peek(); t.kind == tokenIdent && (t.quoted || !reserved[strings.ToUpper(t.text)]) {
+		item.Alias = p.next().text
+	}
+	if strings.Contains(item.Alias, ".") {
+		return SelectItem{}, fmt.Errorf("alias %q must not contain '.'", item.Alias)
+	}
+	return item, nil
+}
+
+func (p *parser) tableRef() (TableRef, error) {
+	name, err := p.identifier("collection name")
+	if err != nil {
+		return TableRef{}, err
+	}
+	table := TableRef{Name: name}
+	if p.acceptKeyword("AS") {
+		if table.Alias, err = p.identifier("alias"); err != nil {
+			return TableRef{}, err
+		}
+	} else if t := p.peek(); t.kind == tokenIdent && (t.quoted || !reserved[strings.ToUpper(t.text)]) {
+		table.Alias = p.next().text
+	}
+	if strings.Contains(table.Alias, ".") {
+		return TableRef{}, fmt.Errorf("alias %q must not contain '.'", table.Alias)
+	}
+	return table, nil
+}
+
+func (p *parser) join(outer bool) (Join, error) {
+	table, err := p.tableRef()
+	if err != nil {
+		return Join{}, err
+	}
+	if err := p.expectKeyword("ON"); err != nil {
+		return Join{}, err
+	}
+	left, err := p.identifier("column")
+	if err != nil {
+		return Join{}, err
+	}
+	if err := p.expectSymbol("="); err != nil {
+		return Join{}, fmt.Errorf("only equality joins are supported: %w", err)
+	}
+	right, err := p.identifier("column")
+	if err != nil {
+		return Join{}, err
+	}
+	return Join{Table: table, Outer: outer, Left: left, Right: right}, nil
+}
+
+// count 读取LIMIT或OFFSET的非负整数
+func (p *parser) count(clause string) (int64, error) {
+	t := p.next()
+	if t.kind == tokenNumber {
+		if n, err := strconv.ParseInt(t.text, 10, 64); err == nil && n >= 0 {
+			return n, nil
+		}
+	}
+	return 0, fmt.Errorf("%s requires a non-negative integer, found %s", clause, t)
+}
+
+// operand 读取字段名或聚合函数调用
+func (p *parser) operand() (Operand, error) {
+	t := p.peek()
+	if t.kind == tokenIdent && !t.quoted && aggregateFuncs[strings.ToUpper(t.text)] &&
+		p.tokens[p.pos+1].kind == tokenSymbol && p.tokens[p.pos+1].text == "(" {
+		p.pos += 2
+		operand := Operand{Func: strings.ToUpper(t.text)}
+		if p.acceptSymbol("*") {
+			if operand.Func != "COUNT" {
+				return Operand{}, fmt.Errorf("%s(*) is not supported", operand.Func)
+			}
+			operand.Column = "*"
+		} else {
+			if p.isKeyword("DISTINCT") {
+				return Operand{}, fmt.Errorf("%s(DISTINCT ...) is not supported", operand.Func)
+			}
+			column, err := p.identifier("column")
+			if err != nil {
+				return Operand{}, err
+			}
+			operand.Column = column
+		}
+		return operand, p.expectSymbol(")")
+	}
+
+	column, err := p.identifier("column")
+	if err != nil {
+		return Operand{}, err
+	}
+	return Operand{Column: column}, nil
+}
+
+// expression 解析条件表达式，优先级为 NOT > AND > OR
+func (p *parser) expression() (Expr, error) {
+	return p.logical("OR", p.and)
+}
+
+func (p *parser) and() (Expr, error) {
+	return p.logical("AND", p.not)
+}
+
+func (p *parser) logical(op string, term func() (Expr, error)) (Expr, error) {
+	first, err := term()
+	if err != nil {
+		return nil, err
+	}
+	terms := []Expr{first}
+	for p.acceptKeyword(op) {
+		next, err := term()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, next)
+	}
+	if len(terms) == 1 {
+		return first, nil
+	}
+	return &Logical{Op: op, Terms: terms}, nil
+}
+
+func (p *parser) not() (Expr, error) {
+	if p.acceptKeyword("NOT") {
+		inner, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return &Not{Expr: inner}, nil
+	}
+	if p.acceptSymbol("(") {
+		inner, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		return inner, p.expectSymbol(")")
+	}
+	return p.predicate()
+}
+
+// predicate 解析单个条件：比较、[NOT] IN、[NOT] LIKE、[NOT] BETWEEN、IS [NOT] NULL
+func (p *parser) predicate() (Expr, error) {
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.acceptKeyword("IS") {
+		not := p.acceptKeyword("NOT")
+		if err := p.expectKeyword("NULL"); err != nil {
+			return nil, err
+		}
+		return &IsNull{Left: left, Not: not}, nil
+	}
+
+	not := p.acceptKeyword("NOT")
+	switch {
+	case p.acceptKeyword("IN"):
+		if err := p.expectSymbol("("); err != nil {
+			return nil, err
+		}
+		in := &In{Left: left, Not: not}
+		for {
+			value, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			in.Values = append(in.Values, value)
+			if !p.acceptSymbol(",") {
+				break
+			}
+		}
+		return in, p.expectSymbol(")")
+	case p.isKeyword("LIKE"), p.isKeyword("ILIKE"):
+		ignoreCase := p.isKeyword("ILIKE")
+		p.next()
+		t := p.next()
+		if t.kind != tokenString {
+			return nil, fmt.Errorf("LIKE requires a string pattern, found %s", t)
+		}
+		return &Like{Left: left, Pattern: t.text, IgnoreCase: ignoreCase, Not: not}, nil
+	case p.acceptKeyword("BETWEEN"):
+		low, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectKeyword("AND"); err != nil {
+			return nil, err
+		}
+		high, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		return &Between{Left: left, Low: low, High: high, Not: not}, nil
+	case not:
+		return nil, fmt.Errorf("expected IN, LIKE or BETWEEN after NOT but found %s", p.peek())
+	}
+
+	t := p.next()
+	switch t.text {
+	case "=", "!=", "<>", "<", "<=", ">", ">=":
+		if t.kind != tokenSymbol {
+			break
+		}
+		if next := p.peek(); next.kind == tokenIdent && next.quoted {
+			return nil, fmt.Errorf("comparing two columns is not supported")
+		}
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		op := t.text
+		if op == "<>" {
+			op = "!="
+		}
+		return &Comparison{Left: left, Op: op, Value: value}, nil
+	}
+	p.pos--
+	return nil, fmt.Errorf("expected a comparison operator after %s but found %s", left, p.peek())
+}
+
+// value 读取字面量：数字、字符串、TRUE、FALSE、NULL、ObjectId('...')、ISODate('...') 或 DATE '...'
+func (p *parser) value() (interface{}, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenString:
+		return t.text, nil
+	case tokenNumber:
+		return parseNumber(t.text, false)
+	case tokenSymbol:
+		if t.text == "-" || t.text == "+" {
+			if n := p.next(); n.kind == tokenNumber {
+				return parseNumber(n.text, t.text == "-")
+			}
+		}
+	case tokenIdent:
+		if t.quoted {
+			return nil, fmt.Errorf("comparing two columns is not supported")
+		}
+		switch strings.ToUpper(t.text) {
+		case "TRUE":
+			return true, nil
+		case "FALSE":
+			return false, nil
+		case "NULL":
+			return nil, nil
+		case "DATE", "TIMESTAMP":
+			if s := p.peek(); s.kind == tokenString {
+				p.next()
+				return parseDate(s.text)
+			}
+		case "OBJECTID", "ISODATE":
+			if err := p.expectSymbol("("); err != nil {
+				return nil, err
+			}
+			s := p.next()
+			if s.kind != tokenString {
+				return nil, fmt.Errorf("%s requires a string argument, found %s", t.text, s)
+			}
+			if err := p.expectSymbol(")"); err != nil {
+				return nil, err
+			}
+			if strings.EqualFold(t.text, "ObjectId") {
+				id, err := primitive.ObjectIDFromHex(s.text)
+				if err != nil {
+					return nil, fmt.Errorf("invalid ObjectId %q", s.text)
+				}
+				return id, nil
+			}
+			return parseDate(s.text)
+		default:
+			return nil, fmt.Errorf("comparing two columns is not supported (found %s where a value was expected)", t)
+		}
+	}
+	p.pos--
+	return nil, fmt.Errorf("expected a value but found %s", t)
+}
+
+// parseNumber 整数在int32范围内时返回int32，与Extended JSON解析数字的结果一致
+func parseNumber(text string, negative bool) (interface{}, error) {
+	if negative {
+		text = "-" + text
+	}
+	if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+		if n >= -1<<31 && n < 1<<31 {
+			return int32(n), nil
+		}
+		return n, nil
+	}
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number %q", text)
+	}
+	return f, nil
+}
+
+// parseDate 解析日期字面量，未带时区时按UTC处理
+func parseDate(text string) (interface{}, error) {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999", "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, text); err == nil {
+			return primitive.NewDateTimeFromTime(t), nil
+		}
+	}
+	return nil, fmt.Errorf("invalid date %q", text)
+}
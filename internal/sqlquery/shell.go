@@ -0,0 +1,105 @@
+package sqlquery
+
+import (
+	"encoding/json"
+	"fmt"
+	"m-db-ui/internal/database"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// shellKey 不需要加引号的字段名
+var shellKey = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// shellValue 将值格式化为mongosh语法：正则表达式写为 /.../flags，日期写为 ISODate("...")，
+// ObjectId写为 ObjectId("...")；其他无法直接书写的类型使用relaxed Extended JSON
+func shellValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bson.D:
+		if len(v) == 0 {
+			return "{}"
+		}
+		fields := make([]string, len(v))
+		for i, e := range v {
+			key := e.Key
+			if !shellKey.MatchString(key) {
+				key = strconv.Quote(key)
+			}
+			fields[i] = key + ": " + shellValue(e.Value)
+		}
+		return "{ " + strings.Join(fields, ", ") + " }"
+	case bson.A:
+		return shellArray(len(v), func(i int) interface{} { return v[i] })
+	case []interface{}:
+		return shellArray(len(v), func(i int) interface{} { return v[i] })
+	case []bson.D:
+		return shellArray(len(v), func(i int) interface{} { return v[i] })
+	case string:
+		quoted, _ := json.Marshal(v)
+		return string(quoted)
+	case bool:
+		return strconv.FormatBool(v)
+	case int32:
+		return strconv.FormatInt(int64(v), 10)
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		// 超出JavaScript安全整数范围时才需要NumberLong，数值比较不区分类型
+		if v > 1<<53 || v < -(1<<53) {
+			return fmt.Sprintf("NumberLong(%q)", strconv.FormatInt(v, 10))
+		}
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case primitive.Regex:
+		return "/" + shellPattern(v.Pattern) + "/" + v.Options
+	case primitive.DateTime:
+		return fmt.Sprintf("ISODate(%q)", v.Time().UTC().Format("2006-01-02T15:04:05.000Z"))
+	case time.Time:
+		return fmt.Sprintf("ISODate(%q)", v.UTC().Format("2006-01-02T15:04:05.000Z"))
+	case primitive.ObjectID:
+		return fmt.Sprintf("ObjectId(%q)", v.Hex())
+	case primitive.Decimal128:
+		return fmt.Sprintf("NumberDecimal(%q)", v.String())
+	}
+	return string(database.ExtJSONValue(v))
+}
+
+func shellArray(n int, item func(int) interface{}) string {
+	if n == 0 {
+		return "[]"
+	}
+	items := make([]string, n)
+	for i := range items {
+		items[i] = shellValue(item(i))
+	}
+	return "[ " + strings.Join(items, ", ") + " ]"
+}
+
+// shellPattern 转义正则表达式字面量中未转义的 /，空模式写为 (?:)
+func shellPattern(pattern string) string {
+	if pattern == "" {
+		return "(?:)"
+	}
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case c == '\\' && i+1 < len(pattern):
+			b.WriteByte(c)
+			i++
+			b.WriteByte(pattern[i])
+		case c == '/':
+			b.WriteString(`\/`)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
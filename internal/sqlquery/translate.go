@@ -0,0 +1,668 @@
+// Package sqlquery 将SQL SELECT语句的子集翻译为MongoDB的find查询或聚合管道
+package sqlquery
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Translation SQL翻译得到的MongoDB查询；Pipeline为nil时是find查询，否则是聚合查询
+type Translation struct {
+	Collection string
+	Filter     bson.D
+	Sort       bson.D
+	Projection bson.D
+	Skip       int64
+	Limit      int64 // 0表示不限制
+	Pipeline   []bson.D
+}
+
+// Aggregate 是否需要使用聚合查询
+func (t *Translation) Aggregate() bool {
+	return t.Pipeline != nil
+}
+
+// Stages 以聚合管道表示查询，find查询转换为等价的 $match、$sort、$skip、$limit、$project 阶段
+func (t *Translation) Stages() []bson.D {
+	if t.Aggregate() {
+		return t.Pipeline
+	}
+	stages := []bson.D{}
+	if len(t.Filter) > 0 {
+		stages = append(stages, bson.D{{Key: "$match", Value: t.Filter}})
+	}
+	if len(t.Sort) > 0 {
+		stages = append(stages, bson.D{{Key: "$sort", Value: t.Sort}})
+	}
+	if t.Skip > 0 {
+		stages = append(stages, bson.D{{Key: "$skip", Value: t.Skip}})
+	}
+	if t.Limit > 0 {
+		stages = append(stages, bson.D{{Key: "$limit", Value: t.Limit}})
+	}
+	if len(t.Projection) > 0 {
+		stages = append(stages, bson.D{{Key: "$project", Value: t.Projection}})
+	}
+	return stages
+}
+
+// simpleName 可以直接写成 db.<name> 的集合名
+var simpleName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Shell 查询的mongosh写法，可以直接粘贴到mongosh中执行
+func (t *Translation) Shell() string {
+	collection := "db." + t.Collection
+	if !simpleName.MatchString(t.Collection) {
+		collection = fmt.Sprintf("db.getCollection(%q)", t.Collection)
+	}
+
+	if t.Aggregate() {
+		return fmt.Sprintf("%s.aggregate(%s)", collection, shellValue(t.Pipeline))
+	}
+	filter := t.Filter
+	if filter == nil {
+		filter = bson.D{}
+	}
+	shell := fmt.Sprintf("%s.find(%s", collection, shellValue(filter))
+	if len(t.Projection) > 0 {
+		shell += ", " + shellValue(t.Projection)
+	}
+	shell += ")"
+	if len(t.Sort) > 0 {
+		shell += fmt.Sprintf(".sort(%s)", shellValue(t.Sort))
+	}
+	if t.Skip > 0 {
+		shell += fmt.Sprintf(".skip(%d)", t.Skip)
+	}
+	if t.Limit > 0 {
+		shell += fmt.Sprintf(".limit(%d)", t.Limit)
+	}
+	return shell
+}
+
+// Translate 解析并翻译SQL语句。
+// 没有JOIN、GROUP BY、聚合函数和列别名时翻译为find查询，否则翻译为聚合管道
+func Translate(sql string) (*Translation, error) {
+	stmt, err := Parse(sql)
+	if err != nil {
+		return nil, err
+	}
+	t := &translator{stmt: stmt, joins: make(map[string]string)}
+	return t.translate()
+}
+
+// translator 翻译过程中的状态
+type translator struct {
+	stmt     *Statement
+	joins    map[string]string // JOIN的集合名和别名 → 连接结果所在的字段
+	joinRefs int               // 引用JOIN字段的次数，用于判断WHERE能否放在 $lookup 之前
+}
+
+func (t *translator) translate() (*Translation, error) {
+	stmt := t.stmt
+	if stmt.Limit == 0 {
+		return nil, fmt.Errorf("LIMIT must be positive")
+	}
+	result := &Translation{Collection: stmt.Table.Name, Skip: stmt.Offset}
+	if stmt.Limit > 0 {
+		result.Limit = stmt.Limit
+	}
+
+	// JOIN 翻译为 $lookup + $unwind，连接的文档放在以别名命名的字段中
+	var lookups []bson.D
+	for _, join := range stmt.Joins {
+		stages, err := t.join(join)
+		if err != nil {
+			return nil, err
+		}
+		lookups = append(lookups, stages...)
+	}
+
+	var filter bson.D
+	var whereUsesJoin bool
+	if stmt.Where != nil {
+		refs := t.joinRefs
+		var err error
+		if filter, err = t.filter(stmt.Where, t.whereField); err != nil {
+			return nil, err
+		}
+		whereUsesJoin = t.joinRefs > refs
+	}
+
+	grouped := len(stmt.GroupBy) > 0
+	aliased := false
+	for _, item := range stmt.Columns {
+		grouped = grouped || item.Func != ""
+		aliased = aliased || (item.Alias != "" && item.Alias != item.Column)
+	}
+	if stmt.Having != nil && !grouped {
+		return nil, fmt.Errorf("HAVING requires GROUP BY or aggregate functions")
+	}
+
+	// 简单查询直接使用find
+	if len(stmt.Joins) == 0 && !grouped && !aliased {
+		result.Filter = filter
+		if result.Filter == nil {
+			result.Filter = bson.D{}
+		}
+		var err error
+		if result.Sort, err = t.sort(t.sortField); err != nil {
+			return nil, err
+		}
+		if result.Projection, err = t.projection(); err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+
+	// WHERE只引用主集合的字段时放在 $lookup 之前，减少连接的文档数
+	pipeline := []bson.D{}
+	match := bson.D{{Key: "$match", Value: filter}}
+	if filter != nil && !whereUsesJoin {
+		pipeline = append(pipeline, match)
+	}
+	pipeline = append(pipeline, lookups...)
+	if filter != nil && whereUsesJoin {
+		pipeline = append(pipeline, match)
+	}
+
+	var tail []bson.D
+	var err error
+	if grouped {
+		tail, err = t.group()
+	} else {
+		tail, err = t.selectColumns()
+	}
+	if err != nil {
+		return nil, err
+	}
+	pipeline = append(pipeline, tail...)
+
+	if stmt.Offset > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$skip", Value: stmt.Offset}})
+	}
+	if stmt.Limit > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$limit", Value: stmt.Limit}})
+	}
+	result.Pipeline = pipeline
+	return result, nil
+}
+
+// field 将SQL中的字段名转换为文档中的字段路径：去掉主集合的前缀，JOIN集合的字段位于连接结果字段下
+func (t *translator) field(column string) (string, error) {
+	if column == "*" {
+		return "", fmt.Errorf("'*' can only be used as SELECT * or COUNT(*)")
+	}
+	if strings.HasPrefix(column, "$") {
+		return "", fmt.Errorf("invalid column %q", column)
+	}
+	prefix, rest, qualified := strings.Cut(column, ".")
+	if !qualified {
+		return column, nil
+	}
+	base := t.stmt.Table
+	if prefix == base.Ref() || prefix == base.Name {
+		return rest, nil
+	}
+	if as, ok := t.joins[prefix]; ok {
+		t.joinRefs++
+		return as + "." + rest, nil
+	}
+	return column, nil
+}
+
+// whereField WHERE中只能使用字段
+func (t *translator) whereField(operand Operand) (string, error) {
+	if operand.Func != "" {
+		return "", fmt.Errorf("aggregate function %s is not allowed in WHERE, use HAVING instead", operand)
+	}
+	return t.field(operand.Column)
+}
+
+func (t *translator) join(join Join) ([]bson.D, error) {
+	as := join.Table.Ref()
+	joined := func(column string) (string, bool) {
+		prefix, rest, ok := strings.Cut(column, ".")
+		if ok && (prefix == as || prefix == join.Table.Name) {
+			return rest, true
+		}
+		return "", false
+	}
+
+	foreign, ok := joined(join.Right)
+	other := join.Left
+	if !ok {
+		if foreign, ok = joined(join.Left); !ok {
+			return nil, fmt.Errorf("JOIN condition must reference a column of %s, e.g. %s.id", join.Table.Name, as)
+		}
+		other = join.Right
+	}
+	local, err := t.field(other)
+	if err != nil {
+		return nil, err
+	}
+
+	t.joins[as] = as
+	t.joins[join.Table.Name] = as
+	return []bson.D{
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: join.Table.Name},
+			{Key: "localField", Value: local},
+			{Key: "foreignField", Value: foreign},
+			{Key: "as", Value: as},
+		}}},
+		{{Key: "$unwind", Value: bson.D{
+			{Key: "path", Value: "$" + as},
+			{Key: "preserveNullAndEmptyArrays", Value: join.Outer},
+		}}},
+	}, nil
+}
+
+// sortField 不分组时ORDER BY可以使用字段或列别名
+func (t *translator) sortField(operand Operand) (string, error) {
+	if operand.Func != "" {
+		return "", fmt.Errorf("aggregate function %s in ORDER BY requires GROUP BY", operand)
+	}
+	for _, item := range t.stmt.Columns {
+		if item.Alias != "" && item.Alias == operand.Column {
+			return t.field(item.Column)
+		}
+	}
+	return t.field(operand.Column)
+}
+
+func (t *translator) sort(resolve func(Operand) (string, error)) (bson.D, error) {
+	var sort bson.D
+	for _, item := range t.stmt.OrderBy {
+		path, err := resolve(item.Operand)
+		if err != nil {
+			return nil, err
+		}
+		direction := 1
+		if item.Desc {
+			direction = -1
+		}
+		sort = append(sort, bson.E{Key: path, Value: direction})
+	}
+	return sort, nil
+}
+
+// projection find查询的投影，只返回选择的字段；没有选择 _id 时排除 _id
+func (t *translator) projection() (bson.D, error) {
+	if len(t.stmt.Columns) == 0 {
+		return nil, nil
+	}
+	projection := bson.D{}
+	hasID := false
+	for _, item := range t.stmt.Columns {
+		path, err := t.field(item.Column)
+		if err != nil {
+			return nil, err
+		}
+		hasID = hasID || path == "_id"
+		projection = append(projection, bson.E{Key: path, Value: 1})
+	}
+	if !hasID {
+		projection = append(projection, bson.E{Key: "_id", Value: 0})
+	}
+	return projection, nil
+}
+
+// selectColumns 不分组的聚合查询：排序后用 $project 选择字段和重命名
+func (t *translator) selectColumns() ([]bson.D, error) {
+	var stages []bson.D
+	sort, err := t.sort(t.sortField)
+	if err != nil {
+		return nil, err
+	}
+	if len(sort) > 0 {
+		stages = append(stages, bson.D{{Key: "$sort", Value: sort}})
+	}
+	if len(t.stmt.Columns) == 0 {
+		return stages, nil
+	}
+
+	project := bson.D{}
+	hasID := false
+	for _, item := range t.stmt.Columns {
+		path, err := t.field(item.Column)
+		if err != nil {
+			return nil, err
+		}
+		if item.Alias == "" || item.Alias == item.Column {
+			hasID = hasID || path == "_id"
+			project = append(project, bson.E{Key: path, Value: 1})
+		} else {
+			hasID = hasID || item.Alias == "_id"
+			project = append(project, bson.E{Key: item.Alias, Value: "$" + path})
+		}
+	}
+	if !hasID {
+		project = append(project, bson.E{Key: "_id", Value: 0})
+	}
+	return append(stages, bson.D{{Key: "$project", Value: project}}), nil
+}
+
+// groupColumn 分组查询结果中的一列
+type groupColumn struct {
+	item   SelectItem
+	path   string // 字段路径，聚合函数为 "*" 或其参数的字段路径
+	output string // 结果中的字段名
+}
+
+// group 分组查询：$group 计算聚合值，$project 把分组键展开为普通字段，HAVING和ORDER BY作用在结果字段上
+func (t *translator) group() ([]bson.D, error) {
+	stmt := t.stmt
+	if len(stmt.Columns) == 0 {
+		return nil, fmt.Errorf("SELECT * cannot be used with GROUP BY")
+	}
+
+	// 分组键在 _id 中的字段名不能包含点号
+	keys := bson.D{}
+	keyNames := make(map[string]string)
+	for _, column := range stmt.GroupBy {
+		path, err := t.field(column)
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := keyNames[path]; exists {
+			continue
+		}
+		name := strings.ReplaceAll(path, ".", "_")
+		keyNames[path] = name
+		keys = append(keys, bson.E{Key: name, Value: "$" + path})
+	}
+
+	var columns []groupColumn
+	outputs := make(map[string]bool)
+	group := bson.D{{Key: "_id", Value: nil}}
+	if len(keys) > 0 {
+		group[0].Value = keys
+	}
+	project := bson.D{}
+	for _, item := range stmt.Columns {
+		column := groupColumn{item: item, path: "*"}
+		if item.Column != "*" {
+			path, err := t.field(item.Column)
+			if err != nil {
+				return nil, err
+			}
+			column.path = path
+		}
+
+		if item.Func == "" {
+			name, ok := keyNames[column.path]
+			if !ok {
+				return nil, fmt.Errorf("column %s must appear in GROUP BY or be used in an aggregate function", item.Column)
+			}
+			column.output = column.path
+			if item.Alias != "" {
+				column.output = item.Alias
+			}
+			project = append(project, bson.E{Key: column.output, Value: "$_id." + name})
+		} else {
+			column.output = item.Alias
+			if column.output == "" {
+				column.output = strings.ToLower(item.Func)
+				if column.path != "*" {
+					column.output += "_" + strings.ReplaceAll(column.path, ".", "_")
+				}
+			}
+			group = append(group, bson.E{Key: column.output, Value: accumulator(item.Func, column.path)})
+			project = append(project, bson.E{Key: column.output, Value: "$" + column.output})
+		}
+
+		if outputs[column.output] {
+			return nil, fmt.Errorf("duplicate column name %q, use AS to rename it", column.output)
+		}
+		outputs[column.output] = true
+		columns = append(columns, column)
+	}
+	if !outputs["_id"] {
+		project = append(project, bson.E{Key: "_id", Value: 0})
+	}
+
+	// HAVING和ORDER BY只能引用选择的列
+	resolve := func(operand Operand) (string, error) {
+		path := "*"
+		if operand.Column != "*" {
+			var err error
+			if path, err = t.field(operand.Column); err != nil {
+				return "", err
+			}
+		}
+		for _, column := range columns {
+			if operand.Func == "" && column.item.Alias != "" && column.item.Alias == operand.Column {
+				return column.output, nil
+			}
+			if column.item.Func == operand.Func && column.path == path {
+				return column.output, nil
+			}
+		}
+		return "", fmt.Errorf("%s must be selected to be used in HAVING or ORDER BY", operand)
+	}
+
+	stages := []bson.D{
+		{{Key: "$group", Value: group}},
+		{{Key: "$project", Value: project}},
+	}
+	if stmt.Having != nil {
+		having, err := t.filter(stmt.Having, resolve)
+		if err != nil {
+			return nil, err
+		}
+		stages = append(stages, bson.D{{Key: "$match", Value: having}})
+	}
+	sort, err := t.sort(resolve)
+	if err != nil {
+		return nil, err
+	}
+	if len(sort) > 0 {
+		stages = append(stages, bson.D{{Key: "$sort", Value: sort}})
+	}
+	return stages, nil
+}
+
+// accumulator 聚合函数对应的 $group 累加器；COUNT(字段) 只统计字段不为null的文档
+func accumulator(fn, path string) bson.D {
+	switch {
+	case fn == "COUNT" && path == "*":
+		return bson.D{{Key: "$sum", Value: 1}}
+	case fn == "COUNT":
+		// 比较顺序中 null 和缺失字段都小于其他值
+		return bson.D{{Key: "$sum", Value: bson.D{{Key: "$cond", Value: bson.A{
+			bson.D{{Key: "$gt", Value: bson.A{"$" + path, nil}}}, 1, 0,
+		}}}}}
+	}
+	return bson.D{{Key: "$" + strings.ToLower(fn), Value: "$" + path}}
+}
+
+// comparisonOps SQL比较运算符对应的查询运算符
+var comparisonOps = map[string]string{"!=": "$ne", "<": "$lt", "<=": "$lte", ">": "$gt", ">=": "$gte"}
+
+// filter 将条件表达式翻译为查询条件，resolve将操作数转换为字段路径
+func (t *translator) filter(expr Expr, resolve func(Operand) (string, error)) (bson.D, error) {
+	switch e := expr.(type) {
+	case *Logical:
+		var docs []bson.D
+		for _, term := range e.Terms {
+			doc, err := t.filter(term, resolve)
+			if err != nil {
+				return nil, err
+			}
+			docs = append(docs, doc)
+		}
+		if e.Op == "AND" {
+			return mergeAnd(docs), nil
+		}
+		var terms bson.A
+		for _, doc := range docs {
+			// 嵌套的OR展开为同一层
+			if len(doc) == 1 && doc[0].Key == "$or" {
+				terms = append(terms, doc[0].Value.(bson.A)...)
+			} else {
+				terms = append(terms, doc)
+			}
+		}
+		return bson.D{{Key: "$or", Value: terms}}, nil
+	case *Not:
+		inner, err := t.filter(e.Expr, resolve)
+		if err != nil {
+			return nil, err
+		}
+		return bson.D{{Key: "$nor", Value: bson.A{inner}}}, nil
+	}
+
+	var left Operand
+	switch e := expr.(type) {
+	case *Comparison:
+		left = e.Left
+	case *In:
+		left = e.Left
+	case *Like:
+		left = e.Left
+	case *Between:
+		left = e.Left
+	case *IsNull:
+		left = e.Left
+	}
+	path, err := resolve(left)
+	if err != nil {
+		return nil, err
+	}
+
+	switch e := expr.(type) {
+	case *Comparison:
+		if e.Op == "=" {
+			return bson.D{{Key: path, Value: e.Value}}, nil
+		}
+		return bson.D{{Key: path, Value: bson.D{{Key: comparisonOps[e.Op], Value: e.Value}}}}, nil
+	case *In:
+		op := "$in"
+		if e.Not {
+			op = "$nin"
+		}
+		return bson.D{{Key: path, Value: bson.D{{Key: op, Value: bson.A(e.Values)}}}}, nil
+	case *Like:
+		regex := likeRegex(e.Pattern, e.IgnoreCase)
+		if e.Not {
+			return bson.D{{Key: path, Value: bson.D{{Key: "$not", Value: regex}}}}, nil
+		}
+		return bson.D{{Key: path, Value: regex}}, nil
+	case *Between:
+		if e.Not {
+			return bson.D{{Key: "$or", Value: bson.A{
+				bson.D{{Key: path, Value: bson.D{{Key: "$lt", Value: e.Low}}}},
+				bson.D{{Key: path, Value: bson.D{{Key: "$gt", Value: e.High}}}},
+			}}}, nil
+		}
+		return bson.D{{Key: path, Value: bson.D{{Key: "$gte", Value: e.Low}, {Key: "$lte", Value: e.High}}}}, nil
+	case *IsNull:
+		if e.Not {
+			return bson.D{{Key: path, Value: bson.D{{Key: "$ne", Value: nil}}}}, nil
+		}
+		return bson.D{{Key: path, Value: nil}}, nil
+	}
+	return nil, fmt.Errorf("unsupported expression %T", expr)
+}
+
+// mergeAnd 合并AND连接的条件：字段不重复时合并为一个文档，同一字段的运算符不冲突时合并，否则使用 $and
+func mergeAnd(docs []bson.D) bson.D {
+	var terms []bson.D
+	for _, doc := range docs {
+		if len(doc) == 1 && doc[0].Key == "$and" {
+			for _, term := range doc[0].Value.(bson.A) {
+				terms = append(terms, term.(bson.D))
+			}
+		} else {
+			terms = append(terms, doc)
+		}
+	}
+
+	merged := bson.D{}
+	index := make(map[string]int)
+	for _, term := range terms {
+		for _, e := range term {
+			i, exists := index[e.Key]
+			if !exists {
+				index[e.Key] = len(merged)
+				merged = append(merged, e)
+				continue
+			}
+			combined, ok := mergeOperators(merged[i].Value, e.Value)
+			if !ok {
+				and := bson.A{}
+				for _, term := range terms {
+					and = append(and, term)
+				}
+				return bson.D{{Key: "$and", Value: and}}
+			}
+			merged[i].Value = combined
+		}
+	}
+	return merged
+}
+
+// mergeOperators 合并同一字段上的两组运算符，如 {$gt: 1} 和 {$lt: 5}；有重复的运算符时不能合并
+func mergeOperators(a, b interface{}) (bson.D, bool) {
+	x, ok := a.(bson.D)
+	if !ok || !isOperators(x) {
+		return nil, false
+	}
+	y, ok := b.(bson.D)
+	if !ok || !isOperators(y) {
+		return nil, false
+	}
+	combined := append(bson.D{}, x...)
+	for _, e := range y {
+		for _, existing := range x {
+			if existing.Key == e.Key {
+				return nil, false
+			}
+		}
+		combined = append(combined, e)
+	}
+	return combined, true
+}
+
+func isOperators(doc bson.D) bool {
+	for _, e := range doc {
+		if !strings.HasPrefix(e.Key, "$") {
+			return false
+		}
+	}
+	return len(doc) > 0
+}
+
+// likeRegex 将LIKE模式转换为正则表达式：% 匹配任意个字符，_ 匹配一个字符，\ 转义下一个字符；去掉多余的首尾 .*
+func likeRegex(pattern string, ignoreCase bool) primitive.Regex {
+	var b strings.Builder
+	b.WriteString("^")
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		switch r := runes[i]; {
+		case r == '%':
+			b.WriteString(".*")
+		case r == '_':
+			b.WriteString(".")
+		case r == '\\' && i+1 < len(runes):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(runes[i])))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+
+	expr := strings.TrimPrefix(b.String(), "^.*")
+	expr = strings.TrimSuffix(expr, ".*$")
+	regex := primitive.Regex{Pattern: expr}
+	if ignoreCase {
+		regex.Options = "i"
+	}
+	return regex
+}
@@ -0,0 +1,113 @@
+package sqlquery
+
+import "testing"
+
+func TestTranslateShell(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want string
+	}{
+		{
+			name: "where",
+			sql:  "SELECT * FROM users WHERE age > 18 AND status = 'active'",
+			want: `db.users.find({ age: { $gt: 18 }, status: "active" })`,
+		},
+		{
+			name: "where range merged",
+			sql:  "SELECT * FROM users WHERE age >= 18 AND age < 65",
+			want: `db.users.find({ age: { $gte: 18, $lt: 65 } })`,
+		},
+		{
+			name: "where or and is null",
+			sql:  "SELECT * FROM `my-coll` WHERE `a.b` = 1 OR c IS NULL",
+			want: `db.getCollection("my-coll").find({ $or: [ { "a.b": 1 }, { c: null } ] })`,
+		},
+		{
+			name: "where objectid and date",
+			sql:  "SELECT * FROM users WHERE _id = ObjectId('5f1d7f3e2b3c4a5d6e7f8091') AND created >= DATE '2024-01-02'",
+			want: `db.users.find({ _id: ObjectId("5f1d7f3e2b3c4a5d6e7f8091"), created: { $gte: ISODate("2024-01-02T00:00:00.000Z") } })`,
+		},
+		{
+			name: "where int64",
+			sql:  "SELECT * FROM users WHERE big = 3000000000",
+			want: `db.users.find({ big: 3000000000 })`,
+		},
+		{
+			name: "like prefix",
+			sql:  "SELECT * FROM users WHERE name LIKE 'Jo%'",
+			want: `db.users.find({ name: /^Jo/ })`,
+		},
+		{
+			name: "like escapes slash",
+			sql:  "SELECT * FROM users WHERE path LIKE '%a/b%'",
+			want: `db.users.find({ path: /a\/b/ })`,
+		},
+		{
+			name: "ilike suffix",
+			sql:  "SELECT * FROM users WHERE name ILIKE '%son'",
+			want: `db.users.find({ name: /son$/i })`,
+		},
+		{
+			name: "not like",
+			sql:  "SELECT * FROM users WHERE name NOT LIKE 'a_c'",
+			want: `db.users.find({ name: { $not: /^a.c$/ } })`,
+		},
+		{
+			name: "in",
+			sql:  "SELECT * FROM users WHERE status IN ('a', 'b')",
+			want: `db.users.find({ status: { $in: [ "a", "b" ] } })`,
+		},
+		{
+			name: "not in",
+			sql:  "SELECT * FROM users WHERE age NOT IN (1, 2)",
+			want: `db.users.find({ age: { $nin: [ 1, 2 ] } })`,
+		},
+		{
+			name: "order by limit offset",
+			sql:  "SELECT name, age FROM users ORDER BY age DESC, name LIMIT 10 OFFSET 20",
+			want: `db.users.find({}, { name: 1, age: 1, _id: 0 }).sort({ age: -1, name: 1 }).skip(20).limit(10)`,
+		},
+		{
+			name: "group by",
+			sql:  "SELECT status, COUNT(*) FROM users GROUP BY status",
+			want: `db.users.aggregate([ { $group: { _id: { status: "$status" }, count: { $sum: 1 } } }, { $project: { status: "$_id.status", count: "$count", _id: 0 } } ])`,
+		},
+		{
+			name: "group by having order limit",
+			sql:  "SELECT status, COUNT(*) AS n FROM users WHERE age > 1 GROUP BY status HAVING n > 5 ORDER BY n DESC LIMIT 3",
+			want: `db.users.aggregate([ { $match: { age: { $gt: 1 } } }, { $group: { _id: { status: "$status" }, n: { $sum: 1 } } }, { $project: { status: "$_id.status", n: "$n", _id: 0 } }, { $match: { n: { $gt: 5 } } }, { $sort: { n: -1 } }, { $limit: 3 } ])`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			translation, err := Translate(tt.sql)
+			if err != nil {
+				t.Fatalf("Translate(%q) error: %v", tt.sql, err)
+			}
+			if got := translation.Shell(); got != tt.want {
+				t.Errorf("Shell()\n got: %s\nwant: %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTranslateErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+	}{
+		{name: "zero limit", sql: "SELECT * FROM users LIMIT 0"},
+		{name: "having without group", sql: "SELECT * FROM users HAVING a > 1"},
+		{name: "ungrouped column", sql: "SELECT name, COUNT(*) FROM users GROUP BY status"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Translate(tt.sql); err == nil {
+				t.Errorf("Translate(%q) succeeded, want error", tt.sql)
+			}
+		})
+	}
+}
@@ -8,8 +8,13 @@ import (
 	"m-db-ui/internal/config"
 	"m-db-ui/internal/database"
 	"m-db-ui/internal/handlers"
+	"m-db-ui/internal/jobs"
+	"m-db-ui/internal/queries"
+	"m-db-ui/internal/references"
 	"html/template"
+	"log/slog"
 	"os"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -21,6 +26,8 @@ func printHelp() {
 	fmt.Println("  m-db-ui [选项]")
 	fmt.Println("")
 	fmt.Println("选项:")
+	fmt.Println("  -config string")
+	fmt.Println("        指定配置文件路径 (默认: config.yaml，不存在时忽略)")
 	fmt.Println("  -host string")
 	fmt.Println("        指定运行的主机IP (默认: 127.0.0.1)")
 	fmt.Println("  -port string")
@@ -32,10 +39,14 @@ func printHelp() {
 	fmt.Println("  m-db-ui                          # 使用默认配置启动")
 	fmt.Println("  m-db-ui -host 0.0.0.0 -port 8080 # 指定主机和端口启动")
 	fmt.Println("  m-db-ui -port 9000               # 指定端口启动")
+	fmt.Println("  m-db-ui -config /etc/m-db-ui.yaml # 使用指定配置文件启动")
+	fmt.Println("")
+	fmt.Println("配置优先级: 默认值 < 配置文件 < 环境变量 < 命令行参数")
 }
 
 func main() {
 	// 定义命令行参数
+	configPath := flag.String("config", "config.yaml", "指定配置文件路径")
 	host := flag.String("host", "127.0.0.1", "指定运行的主机IP")
 	port := flag.String("port", "8082", "指定运行的端口")
 	help := flag.Bool("help", false, "显示帮助信息")
@@ -51,12 +62,31 @@ func main() {
 		os.Exit(0)
 	}
 
-	// 设置环境变量，覆盖默认配置
-	os.Setenv("HOST", *host)
-	os.Setenv("PORT", *port)
+	// 加载配置，显式指定的配置文件必须存在
+	configRequired := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "config" {
+			configRequired = true
+		}
+	})
+	cfg, err := config.Load(*configPath, configRequired)
+	if err != nil {
+		log.Fatal("Failed to load config: ", err)
+	}
 
-	// 加载配置
-	cfg := config.Load()
+	// 命令行参数优先级最高，只覆盖显式指定的值
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "host":
+			cfg.Server.Host = *host
+		case "port":
+			cfg.Server.Port = *port
+		}
+	})
+
+	// 初始化日志，标准库log的输出也会转到该日志记录器
+	logger := cfg.Logging.NewLogger()
+	slog.SetDefault(logger)
 
 	// 初始化连接管理器
 	connectionManager := config.NewConnectionManager("connections.json")
@@ -72,20 +102,52 @@ func main() {
 	}
 
 	// 初始化MongoDB连接
-	mongoClient, err := database.Connect(currentConn.GetURI())
+	mongoClient, err := database.Connect(currentConn.GetURI(), cfg.MongoDB.ConnectOptions())
 	if err != nil {
 		log.Fatal("Failed to connect to MongoDB:", err)
 	}
+	logger.Info("Connected to MongoDB", "connection", currentConn.Name)
 	defer mongoClient.Disconnect(context.Background())
 
 	// 初始化服务
-	dbService := database.NewService(mongoClient)
+	dbService := database.NewService(mongoClient, cfg.MongoDB.Timeouts())
+
+	// 初始化后台任务管理器，上次未完成的任务会被标记为已中断
+	jobManager := jobs.NewManager("jobs.json")
+	if err := jobManager.Load(); err != nil {
+		log.Printf("Failed to load jobs: %v", err)
+	}
+
+	// 初始化查询历史和保存的查询
+	queryStore := queries.NewStore("queries.json")
+	if err := queryStore.Load(); err != nil {
+		log.Printf("Failed to load queries: %v", err)
+	}
+
+	// 初始化引用映射
+	referenceStore := references.NewStore("references.json")
+	if err := referenceStore.Load(); err != nil {
+		log.Printf("Failed to load reference mappings: %v", err)
+	}
+
+	// 定期清理回收站中超过保留期限的条目
+	if cfg.Trash.Enabled && cfg.Trash.RetentionDays > 0 {
+		go sweepTrash(dbService.Trash(cfg.Trash.Database), cfg.Trash.Retention(), cfg.Trash.SweepInterval, logger)
+	}
 
 	// 初始化处理器
-	h := handlers.New(dbService, connectionManager)
+	h := handlers.New(cfg, dbService, connectionManager, jobManager, queryStore, referenceStore)
 
 	// 设置Gin路由
-	r := gin.Default()
+	if !cfg.Logging.IsDebug() {
+		gin.SetMode(gin.ReleaseMode)
+	}
+	r := gin.New()
+	// 只有受信任的代理传入的X-Forwarded-For才用于确定客户端IP
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		log.Fatal("Invalid trusted proxies:", err)
+	}
+	r.Use(handlers.RequestLogger(logger), gin.Recovery())
 
 	// 添加模板函数
 	r.SetFuncMap(template.FuncMap{
@@ -98,11 +160,17 @@ func main() {
 	})
 
 	// 配置CORS
-	r.Use(cors.New(cors.Config{
-		AllowAllOrigins: true,
-		AllowMethods:    []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:    []string{"Origin", "Content-Type", "Accept", "Authorization"},
-	}))
+	corsConfig := cors.Config{
+		AllowMethods:  cfg.CORS.AllowedMethods,
+		AllowHeaders:  cfg.CORS.AllowedHeaders,
+		ExposeHeaders: []string{"ETag"},
+	}
+	if cfg.CORS.AllowAll() {
+		corsConfig.AllowAllOrigins = true
+	} else {
+		corsConfig.AllowOrigins = cfg.CORS.AllowedOrigins
+	}
+	r.Use(cors.New(corsConfig))
 
 	// 静态文件服务
 	r.Static("/static", "./web/static")
@@ -134,14 +202,85 @@ func main() {
 		api.GET("/db/:db/collections", h.GetCollections)
 		api.POST("/db/:db/collections", h.CreateCollection)
 		api.DELETE("/db/:db/collections/:collection", h.DeleteCollection)
+		api.GET("/db/:db/diagram", h.GetDiagram)
 
 		// 文档相关
 		api.GET("/db/:db/collections/:collection/documents", h.GetDocuments)
 		api.GET("/db/:db/collections/:collection/documents/:id", h.GetDocument)
 		api.POST("/db/:db/collections/:collection/documents", h.CreateDocument)
-		api.PUT("/db/:db/collections/:collection/documents/:id", h.UpdateDocument)
+		api.PUT("/db/:db/collections/:collection/documents/:id", h.ReplaceDocument)
+		api.PATCH("/db/:db/collections/:collection/documents/:id", h.PatchDocument)
 		api.DELETE("/db/:db/collections/:collection/documents/:id", h.DeleteDocument)
 		api.POST("/db/:db/collections/:collection/query", h.QueryDocuments)
+		api.POST("/db/:db/collections/:collection/codegen", h.GenerateCode)
+
+		// 文档历史版本
+		api.GET("/db/:db/collections/:collection/documents/:id/revisions", h.GetRevisions)
+		api.GET("/db/:db/collections/:collection/documents/:id/revisions/:rev", h.GetRevision)
+		api.GET("/db/:db/collections/:collection/documents/:id/revisions/:rev/diff", h.DiffRevisions)
+		api.POST("/db/:db/collections/:collection/documents/:id/revisions/:rev/rollback", h.RollbackRevision)
+
+		// 批量更新/删除（先预览，再凭令牌执行）
+		api.POST("/db/:db/collections/:collection/bulk/preview", h.PreviewBulk)
+		api.POST("/db/:db/collections/:collection/bulk/execute", h.ExecuteBulk)
+
+		// 结构分析
+		api.GET("/db/:db/collections/:collection/schema", h.AnalyzeSchema)
+		api.GET("/db/:db/collections/:collection/fields", h.GetFieldPaths)
+		api.GET("/db/:db/collections/:collection/query-indexes", h.GetQueryIndexes)
+		api.GET("/db/:db/collections/:collection/schema/jsonschema", h.ExportJSONSchema)
+		api.GET("/db/:db/collections/:collection/schema/gostruct", h.ExportGoStructs)
+
+		// 校验规则
+		api.GET("/db/:db/collections/:collection/validator", h.GetValidator)
+		api.PUT("/db/:db/collections/:collection/validator", h.UpdateValidator)
+		api.POST("/db/:db/collections/:collection/validator/generate", h.GenerateValidator)
+		api.POST("/db/:db/collections/:collection/validator/scan", h.ScanValidator)
+
+		// 变更流实时推送
+		api.GET("/watch", h.WatchChanges)
+		api.GET("/db/:db/watch", h.WatchChanges)
+		api.GET("/db/:db/collections/:collection/watch", h.WatchChanges)
+
+		// 后台任务
+		api.GET("/jobs", h.GetJobs)
+		api.GET("/jobs/:id", h.GetJob)
+		api.GET("/jobs/:id/events", h.StreamJob)
+		api.DELETE("/jobs/:id", h.DeleteJob)
+
+		// 回收站
+		api.GET("/trash", h.GetTrash)
+		api.DELETE("/trash", h.EmptyTrash)
+		api.POST("/trash/:id/restore", h.RestoreTrash)
+		api.DELETE("/trash/:id", h.PurgeTrash)
+
+		// 查询历史和保存的查询
+		api.GET("/queries/history", h.GetQueryHistory)
+		api.DELETE("/queries/history", h.ClearQueryHistory)
+		api.GET("/queries/saved", h.GetSavedQueries)
+		api.POST("/queries/saved", h.CreateSavedQuery)
+		api.PUT("/queries/saved/:id", h.UpdateSavedQuery)
+		api.DELETE("/queries/saved/:id", h.DeleteSavedQuery)
+
+		// 文档引用
+		api.POST("/db/:db/collections/:collection/references", h.ResolveReferences)
+		api.GET("/db/:db/collections/:collection/documents/:id/referrers", h.GetReferrers)
+		api.GET("/references/mappings", h.GetReferenceMappings)
+		api.POST("/references/mappings", h.CreateReferenceMapping)
+		api.DELETE("/references/mappings/:id", h.DeleteReferenceMapping)
+
+		// 全局搜索
+		api.POST("/search", h.Search)
+
+		// 数据库命令
+		api.GET("/commands", h.GetCommands)
+		api.POST("/db/:db/command", h.RunCommand)
+
+		// SQL查询翻译
+		api.POST("/db/:db/sql", h.QuerySQL)
+
+		// JavaScript脚本控制台（WebSocket）
+		api.GET("/shell", h.ShellSocket)
 	}
 
 	// Web界面路由
@@ -149,9 +288,29 @@ func main() {
 	r.GET("/connections", h.ConnectionsPage)
 	r.GET("/database/:db", h.DatabasePage)
 	r.GET("/database/:db/collection/:collection", h.CollectionPage)
+	r.GET("/trash", h.TrashPage)
+	r.GET("/console", h.ConsolePage)
+	r.GET("/shell", h.ShellPage)
+	r.GET("/search", h.SearchPage)
 
 	// 启动服务器
-	address := cfg.Host + ":" + cfg.Port
-	log.Printf("Server starting on %s", address)
+	address := cfg.Server.Host + ":" + cfg.Server.Port
+	logger.Info("Server starting", "address", address)
 	log.Fatal(r.Run(address))
-}
\ No newline at end of file
+}
+
+// sweepTrash 按间隔彻底删除回收站中超过保留期限的条目
+func sweepTrash(trash *database.Trash, retention, interval time.Duration, logger *slog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := trash.Sweep(context.Background(), time.Now().Add(-retention))
+		if err != nil {
+			logger.Error("Failed to sweep recycle bin", "error", err)
+		} else if purged > 0 {
+			logger.Info("Swept recycle bin", "purged", purged)
+		}
+		<-ticker.C
+	}
+}